@@ -0,0 +1,111 @@
+// backend/cmd/migrate/main.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"backend/migrations"
+	"backend/pkg/config"
+	"backend/pkg/database"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up             Apply all pending migrations
+  down [N]       Roll back the last N applied migrations (default 1)
+  status         List migrations and whether they are applied
+  to <VERSION>   Migrate up or down so that exactly versions <= VERSION are applied
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Fatalf("FATAL: could not load configuration: %v", err)
+	}
+
+	// Migrations run on a single connection, so a minimal pool is enough.
+	pool, err := database.Connect(context.Background(), cfg.Database.URL, database.PoolOptions{MaxConns: 2})
+	if err != nil {
+		log.Fatalf("FATAL: could not connect to database: %v", err)
+	}
+	db := database.OpenDB(pool)
+	defer pool.Close()
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("FATAL: could not load migrations: %v", err)
+	}
+
+	if err := run(context.Background(), migrator, os.Args[1:]); err != nil {
+		pool.Close()
+		log.Fatalf("FATAL: %v", err)
+	}
+}
+
+func run(ctx context.Context, migrator *database.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s).", n)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down expects a positive number of steps, got %q", args[1])
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back %d migration(s).", n)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d  %-45s %s\n", s.Version, s.Name, applied)
+		}
+
+	case "to":
+		if len(args) < 2 {
+			return errors.New("to expects a version")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		n, err := migrator.To(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated to version %d (%d migration(s) run).", version, n)
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return nil
+}
@@ -2,64 +2,370 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"backend/internal/handler"
 	"backend/internal/repository"
 	"backend/internal/service"
+	"backend/migrations"
+	"backend/pkg/auth"
+	"backend/pkg/cache"
 	"backend/pkg/config"
 	"backend/pkg/database"
+	"backend/pkg/health"
+	"backend/pkg/logging"
+	"backend/pkg/mailer"
+	"backend/pkg/metrics"
+	"backend/pkg/tracing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
-	log.Println("Starting AminNCo API Server...")
+	configFile := flag.String("config", "", "path to a YAML config file (default $CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	flag.Parse()
 
 	// 1. Load Configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		log.Fatalf("FATAL: could not load configuration: %v", err)
+		fatal("could not load configuration", err)
+	}
+	if *printConfig {
+		out, err := yaml.Marshal(cfg.Redacted())
+		if err != nil {
+			fatal("could not print configuration", err)
+		}
+		os.Stdout.Write(out)
+		return
 	}
 
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("could not configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+	})
+	if err != nil {
+		fatal("could not configure tracing", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("could not flush traces", slog.Any("error", err))
+		}
+	}()
+	logger.Info("starting AminNCo API server", slog.String("profile", cfg.Profile))
+
 	// 2. Connect to Database
-	db, err := database.Connect(cfg.DatabaseURL)
+	db, err := database.Connect(context.Background(), cfg.Database.URL, database.PoolOptions{
+		MaxConns:              int32(cfg.Database.MaxOpenConns),
+		MinConns:              int32(cfg.Database.MinConns),
+		MaxConnLifetime:       cfg.Database.ConnMaxLifetime,
+		MaxConnIdleTime:       cfg.Database.ConnMaxIdleTime,
+		HealthCheckPeriod:     cfg.Database.HealthCheckPeriod,
+		DisableStatementCache: !cfg.Database.StatementCache,
+	})
 	if err != nil {
-		log.Fatalf("FATAL: could not connect to database: %v", err)
+		fatal("could not connect to database", err)
 	}
 	defer db.Close()
-	log.Println("Database connection successful.")
+	database.RegisterPoolMetrics(metrics.Default, db)
+	logger.Info("database connection successful")
+
+	if cfg.Database.MigrateOnStart {
+		migrator, err := database.NewMigrator(database.OpenDB(db), migrations.FS)
+		if err != nil {
+			fatal("could not load migrations", err)
+		}
+		n, err := migrator.Up(context.Background())
+		if err != nil {
+			fatal("could not apply migrations", err)
+		}
+		logger.Info("applied pending migrations", slog.Int("count", n))
+	}
 
 	// 3. Initialize Repositories (Database Layer)
 	userRepo := repository.NewPostgresUserRepository(db)
 	productRepo := repository.NewPostgresProductRepository(db)
 	storeRepo := repository.NewPostgresStoreRepository(db)
 	adminRepo := repository.NewPostgresAdminRepository(db)
-	// logRepo := repository.NewPostgresLogRepository(db) // For later
+	userTokenRepo := repository.NewPostgresUserTokenRepository(db)
+	logRepo := repository.NewPostgresLogRepository(db)
+	priceRepo := repository.NewPostgresPriceRepository(db)
+	txManager := repository.NewPostgresTxManager(db)
+
+	var loginAttemptRepo repository.LoginAttemptRepository
+	if cfg.Login.ThrottleStore == "postgres" {
+		loginAttemptRepo = repository.NewPostgresLoginAttemptRepository(db)
+	} else {
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepository()
+	}
+
+	var rateLimitRepo repository.RateLimitRepository
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimitRepo = repository.NewPostgresRateLimitRepository(db)
+	} else {
+		rateLimitRepo = repository.NewMemoryRateLimitRepository()
+	}
+
+	// Outgoing email: SMTP in deployed environments, log/.eml files locally.
+	var mail mailer.Mailer
+	if cfg.Mail.Driver == "smtp" {
+		mail = mailer.NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+	} else {
+		mail = mailer.NewLogMailer(cfg.Mail.From, cfg.Mail.Dir)
+	}
+
+	passwordPolicy, err := service.NewPasswordPolicy(cfg.Password.MinLength, cfg.Password.BreachedListFile)
+	if err != nil {
+		fatal("could not load password policy", err)
+	}
+
+	tokens, err := newTokenManager(cfg.JWT)
+	if err != nil {
+		fatal("could not load JWT keys", err)
+	}
 
 	// 4. Initialize Services (Business Logic Layer)
 	// Services can be composed of multiple repositories.
-	userService := service.NewUserService(userRepo)
-	catalogService := service.NewCatalogService(productRepo)
+	userService := service.NewUserService(userRepo, userTokenRepo, txManager, mail, service.UserServiceOptions{
+		AppBaseURL:     cfg.AppBaseURL,
+		BcryptCost:     cfg.Password.BcryptCost,
+		PasswordPolicy: passwordPolicy,
+		Tokens:         tokens,
+	})
+	var catalogCache *service.CatalogCache
+	if cfg.Cache.CatalogTTL > 0 {
+		catalogCache = service.NewCatalogCache(cache.NewLRU(cfg.Cache.MaxEntries), cfg.Cache.CatalogTTL)
+	}
+	catalogService := service.NewCatalogService(productRepo, catalogCache)
 	storeService := service.NewStoreService(storeRepo)
-	adminService := service.NewAdminService(adminRepo)
+	adminService := service.NewAdminService(adminRepo, logRepo, txManager, catalogCache)
+	pricingService := service.NewPricingService(adminRepo, productRepo, priceRepo, logRepo, txManager, catalogCache)
+	rateLimitService := service.NewRateLimitService(rateLimitRepo)
+	authService := service.NewAuthService(userRepo, loginAttemptRepo, logRepo, tokens, service.LockoutPolicy{
+		MaxAccountFailures: cfg.Login.MaxAccountFailures,
+		MaxIPFailures:      cfg.Login.MaxIPFailures,
+		Window:             cfg.Login.FailureWindow,
+		LockoutDuration:    cfg.Login.LockoutDuration,
+	})
 
 	// 5. Initialize Handlers (HTTP Layer)
 	userHandler := handler.NewUserHandler(userService)
 	catalogHandler := handler.NewCatalogHandler(catalogService)
 	storeHandler := handler.NewStoreHandler(storeService)
-	adminHandler := handler.NewAdminHandler(adminService)
+	adminHandler := handler.NewAdminHandler(adminService, pricingService)
+	authHandler := handler.NewAuthHandler(authService)
 
 	// 6. Setup Router and Server, injecting all handlers
+	checks := health.NewRegistry()
+	checks.Register("database", true, 2*time.Second, database.HealthCheck(db))
+	readiness := handler.NewReadiness(checks)
+	routerOpts := handler.RouterOptions{
+		Logger:              logger,
+		RegistrationEnabled: cfg.Features.Registration,
+		APIDocs:             cfg.Features.APIDocs,
+		Compression:         cfg.Server.Compression,
+		CacheControl: handler.CacheControlPolicies{
+			Products:   cfg.HTTPCache.Products,
+			Product:    cfg.HTTPCache.Product,
+			Categories: cfg.HTTPCache.Categories,
+		},
+		CORS: handler.CORSOptions{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		},
+		SecurityHeaders: handler.SecurityHeadersOptions{
+			HSTSMaxAge:            cfg.Security.HSTSMaxAge,
+			HSTSIncludeSubdomains: cfg.Security.HSTSIncludeSubdomains,
+			FrameAncestors:        cfg.Security.FrameAncestors,
+			ReferrerPolicy:        cfg.Security.ReferrerPolicy,
+		},
+	}
+	if cfg.Features.Metrics {
+		routerOpts.Metrics = metrics.Default.Handler()
+	}
+	if cfg.RateLimit.Enabled {
+		routerOpts.RateLimiter = rateLimitService
+		routerOpts.RateLimits = handler.RateLimitPolicies{
+			Auth:    service.RateLimitPolicy{Name: "auth", Requests: cfg.RateLimit.AuthRequests, Period: cfg.RateLimit.AuthPeriod},
+			Catalog: service.RateLimitPolicy{Name: "catalog", Requests: cfg.RateLimit.CatalogRequests, Period: cfg.RateLimit.CatalogPeriod},
+			API:     service.RateLimitPolicy{Name: "api", Requests: cfg.RateLimit.APIRequests, Period: cfg.RateLimit.APIPeriod},
+		}
+	}
 	router := handler.NewRouter(
 		userHandler,
 		catalogHandler,
 		storeHandler,
 		adminHandler,
+		authHandler,
+		readiness,
+		routerOpts,
 	)
 
+	srv := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	// Background workers get their own context, cancelled only after the
+	// server has finished draining so they never see a half-shut-down app.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	startWorker(workerCtx, &workers, "token-cleanup", time.Hour, func(ctx context.Context) error {
+		n, err := userService.PurgeExpiredTokens(ctx)
+		if err == nil && n > 0 {
+			logger.Info("removed expired tokens", slog.String("worker", "token-cleanup"), slog.Int64("count", n))
+		}
+		return err
+	})
+
+	startWorker(workerCtx, &workers, "price-scheduler", cfg.Pricing.SchedulerInterval, func(ctx context.Context) error {
+		n, err := pricingService.ApplyScheduledChanges(ctx, time.Now())
+		if err == nil && n > 0 {
+			logger.Info("applied scheduled price changes", slog.String("worker", "price-scheduler"), slog.Int("count", n))
+		}
+		return err
+	})
+
+	if cfg.RateLimit.Enabled && cfg.RateLimit.Store == "postgres" {
+		startWorker(workerCtx, &workers, "rate-limit-cleanup", 10*time.Minute, func(ctx context.Context) error {
+			_, err := rateLimitService.PurgeIdleBuckets(ctx, time.Hour)
+			return err
+		})
+	}
+
 	// 7. Start the server
-	log.Printf("Server starting on http://localhost:%s", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, router); err != nil {
-		log.Fatalf("FATAL: could not start server: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		if cfg.Server.TLSCertFile != "" {
+			logger.Info("server starting", slog.String("addr", "https://localhost:"+cfg.Server.Port))
+			serverErr <- srv.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			logger.Info("server starting", slog.String("addr", "http://localhost:"+cfg.Server.Port))
+			serverErr <- srv.ListenAndServe()
+		}
+	}()
+
+	// 8. Wait for a shutdown signal, then drain
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErr:
+		// Returning (rather than calling fatal) lets the deferred db.Close run.
+		logger.Error("server stopped unexpectedly", slog.Any("error", err))
+		stopWorkers()
+		workers.Wait()
+		return
+	case <-signalCtx.Done():
+	}
+	stopSignals() // A second signal now kills the process immediately.
+
+	logger.Info("shutdown signal received; failing readiness before draining", slog.Duration("drain_period", cfg.Server.ShutdownDrainPeriod))
+	readiness.StartDraining()
+	time.Sleep(cfg.Server.ShutdownDrainPeriod)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown did not complete", slog.Any("error", err))
 	}
+
+	stopWorkers()
+	workers.Wait()
+	logger.Info("server stopped")
+}
+
+// startWorker runs fn every interval until ctx is cancelled. Errors are logged
+// and the worker keeps going.
+func startWorker(ctx context.Context, wg *sync.WaitGroup, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					slog.Error("background worker failed", slog.String("worker", name), slog.Any("error", err))
+				}
+			}
+		}
+	}()
+}
+
+// fatal logs err and exits. Before logging is configured it goes through
+// slog's default text logger on stderr.
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
+// newTokenManager loads the JWT signing key and any extra verification keys
+// kept around during a key rotation.
+func newTokenManager(cfg config.JWTConfig) (*auth.TokenManager, error) {
+	var signingKey *auth.Key
+	var err error
+	if cfg.SigningKeyFile != "" {
+		signingKey, err = auth.LoadKeyFile(cfg.KeyID, cfg.SigningKeyFile)
+	} else {
+		signingKey, err = auth.NewHMACKey(cfg.KeyID, []byte(cfg.Secret))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !signingKey.CanSign() {
+		return nil, errors.New("JWT_SIGNING_KEY_FILE must contain a private key or secret, not a public key")
+	}
+
+	var verificationKeys []*auth.Key
+	for kid, path := range cfg.VerificationKeys {
+		key, err := auth.LoadKeyFile(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	return auth.NewTokenManager(auth.TokenConfig{
+		SigningKey:       signingKey,
+		VerificationKeys: verificationKeys,
+		TTL:              cfg.TTL,
+		Issuer:           cfg.Issuer,
+		Audience:         cfg.Audience,
+		Leeway:           30 * time.Second,
+	})
 }
@@ -2,54 +2,390 @@
 package handler
 
 import (
+	"backend/internal/repository"
 	"backend/internal/service"
 	"backend/pkg/jsonutil"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type AdminHandler struct {
-	adminService *service.AdminService
+	adminService   *service.AdminService
+	pricingService *service.PricingService
 }
 
-func NewAdminHandler(s *service.AdminService) *AdminHandler {
-	return &AdminHandler{adminService: s}
+func NewAdminHandler(s *service.AdminService, p *service.PricingService) *AdminHandler {
+	return &AdminHandler{adminService: s, pricingService: p}
 }
 
 // CreateProduct handles POST /api/v1/admin/products
 func (h *AdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateProductRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
 		return
 	}
 
-	product, err := h.adminService.CreateProduct(r.Context(), req)
+	actorID, _ := userIDFromContext(r.Context())
+	product, err := h.adminService.CreateProduct(r.Context(), req, actorID, clientInfo(r))
 	if err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 
 	jsonutil.RespondWithJSON(w, http.StatusCreated, product)
 }
 
+// bulkMaxBodyBytes allows imports of up to 1000 products with long descriptions.
+const bulkMaxBodyBytes = 8 << 20
+
+// BulkCreateProducts handles POST /api/v1/admin/products/bulk
+func (h *AdminHandler) BulkCreateProducts(w http.ResponseWriter, r *http.Request) {
+	var req service.BulkCreateProductsRequest
+	if err := jsonutil.DecodeWithLimit(w, r, &req, bulkMaxBodyBytes); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	created, err := h.adminService.BulkCreateProducts(r.Context(), req, actorID, clientInfo(r))
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusCreated, map[string]int64{"created": created})
+}
+
 // AdjustInventory handles PATCH /api/v1/admin/products/{id}/inventory
 func (h *AdminHandler) AdjustInventory(w http.ResponseWriter, r *http.Request) {
 	productID := chi.URLParam(r, "id")
 
 	var req service.AdjustInventoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
 		return
 	}
 
-	newStock, err := h.adminService.AdjustInventory(r.Context(), productID, req.Change)
+	actorID, _ := userIDFromContext(r.Context())
+	newStock, err := h.adminService.AdjustInventory(r.Context(), productID, req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrProductNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if errors.Is(err, repository.ErrMultipleVariants) {
+		jsonutil.RespondWithError(w, http.StatusConflict, "Product has several variants; adjust the inventory of one of them")
+		return
+	}
 	if err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 
 	jsonutil.RespondWithJSON(w, http.StatusOK, map[string]int{"new_inventory_count": newStock})
 }
+
+// UpdateProduct handles PUT /api/v1/admin/products/{id}
+func (h *AdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	var req service.UpdateProductRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	product, err := h.adminService.UpdateProduct(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrProductNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, product)
+}
+
+// DeleteProduct handles DELETE /api/v1/admin/products/{id}
+func (h *AdminHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := userIDFromContext(r.Context())
+	err := h.adminService.DeleteProduct(r.Context(), chi.URLParam(r, "id"), actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrProductNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not delete product")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateVariant handles POST /api/v1/admin/products/{id}/variants
+func (h *AdminHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	var req service.VariantRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	variant, err := h.adminService.CreateVariant(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrProductNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusCreated, variant)
+}
+
+// UpdateVariant handles PUT /api/v1/admin/variants/{id}
+func (h *AdminHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	var req service.UpdateVariantRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	variant, err := h.adminService.UpdateVariant(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, variant)
+}
+
+// DeleteVariant handles DELETE /api/v1/admin/variants/{id}
+func (h *AdminHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := userIDFromContext(r.Context())
+	err := h.adminService.DeleteVariant(r.Context(), chi.URLParam(r, "id"), actorID, clientInfo(r))
+	switch {
+	case errors.Is(err, repository.ErrVariantNotFound):
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	case errors.Is(err, service.ErrLastVariant):
+		jsonutil.RespondWithError(w, http.StatusConflict, "A product must keep at least one variant; delete the product instead")
+		return
+	case err != nil:
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not delete variant")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdjustVariantInventory handles PATCH /api/v1/admin/variants/{id}/inventory
+func (h *AdminHandler) AdjustVariantInventory(w http.ResponseWriter, r *http.Request) {
+	var req service.AdjustInventoryRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	newStock, err := h.adminService.AdjustVariantInventory(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, map[string]int{"new_inventory_count": newStock})
+}
+
+// SetPrice handles PUT /api/v1/admin/variants/{id}/price
+func (h *AdminHandler) SetPrice(w http.ResponseWriter, r *http.Request) {
+	var req service.SetPriceRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	variant, err := h.pricingService.SetPrice(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, variant)
+}
+
+// SchedulePriceChange handles POST /api/v1/admin/variants/{id}/price-changes
+func (h *AdminHandler) SchedulePriceChange(w http.ResponseWriter, r *http.Request) {
+	var req service.SchedulePriceRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	change, err := h.pricingService.SchedulePriceChange(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusCreated, change)
+}
+
+// CancelPriceChange handles DELETE /api/v1/admin/price-changes/{id}
+func (h *AdminHandler) CancelPriceChange(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := userIDFromContext(r.Context())
+	err := h.pricingService.CancelPriceChange(r.Context(), chi.URLParam(r, "id"), actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrPriceChangeNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Scheduled price change not found")
+		return
+	}
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not cancel the price change")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PriceHistory handles GET /api/v1/admin/variants/{id}/price-history
+func (h *AdminHandler) PriceHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	history, err := h.pricingService.PriceHistory(r.Context(), chi.URLParam(r, "id"), service.PriceHistoryQuery{Page: page, Limit: limit})
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve the price history")
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, history)
+}
+
+// SetSale handles PUT /api/v1/admin/variants/{id}/sale
+func (h *AdminHandler) SetSale(w http.ResponseWriter, r *http.Request) {
+	var req service.SaleRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	variant, err := h.pricingService.SetSale(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, variant)
+}
+
+// EndSale handles DELETE /api/v1/admin/variants/{id}/sale
+func (h *AdminHandler) EndSale(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := userIDFromContext(r.Context())
+	err := h.pricingService.EndSale(r.Context(), chi.URLParam(r, "id"), actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrVariantNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not end the sale")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeUserRole handles PUT /api/v1/admin/users/{id}/role
+func (h *AdminHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	var req service.ChangeRoleRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	actorID, _ := userIDFromContext(r.Context())
+	err := h.adminService.ChangeUserRole(r.Context(), chi.URLParam(r, "id"), req, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAuditLog handles GET /api/v1/admin/audit
+func (h *AdminHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	entries, err := h.adminService.ListAuditLog(r.Context(), service.AuditLogQuery{
+		ActorID:    query.Get("actor_id"),
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+		From:       query.Get("from"),
+		To:         query.Get("to"),
+		Page:       page,
+		Limit:      limit,
+	})
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		respondWithValidationError(w, verr)
+		return
+	}
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve the audit log")
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,79 @@
+// backend/internal/handler/auth_handler.go
+package handler
+
+import (
+	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/pkg/jsonutil"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AuthHandler struct {
+	authService *service.AuthService
+}
+
+func NewAuthHandler(s *service.AuthService) *AuthHandler {
+	return &AuthHandler{authService: s}
+}
+
+// Login handles POST /api/v1/users/login
+// Accounts that haven't verified their email address get a 403; they can ask
+// for a new link at POST /api/v1/users/verify-email/request.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req service.LoginRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	res, err := h.authService.Login(r.Context(), req, clientInfo(r))
+	if err != nil {
+		var throttled *service.ThrottledError
+		var verr *service.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			respondWithValidationError(w, verr)
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			jsonutil.RespondWithError(w, http.StatusTooManyRequests, throttled.Error())
+		case errors.Is(err, service.ErrInvalidCredentials):
+			jsonutil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, service.ErrEmailNotVerified):
+			jsonutil.RespondWithError(w, http.StatusForbidden, "Email address is not verified")
+		default:
+			respondWithServiceError(w, r, err)
+		}
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, res)
+}
+
+// UnlockAccount handles POST /api/v1/admin/users/{id}/unlock
+func (h *AuthHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	actorID, _ := userIDFromContext(r.Context())
+
+	err := h.authService.UnlockAccount(r.Context(), userID, actorID, clientInfo(r))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS handles GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Short cache so verifiers pick up a newly added key soon after a rotation starts.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	jsonutil.RespondWithJSON(w, http.StatusOK, h.authService.JWKS())
+}
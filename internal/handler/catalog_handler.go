@@ -2,10 +2,15 @@
 package handler
 
 import (
+	"backend/internal/repository"
 	"backend/internal/service"
 	"backend/pkg/jsonutil"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type CatalogHandler struct {
@@ -34,7 +39,24 @@ func (h *CatalogHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonutil.RespondWithJSON(w, http.StatusOK, products)
+	// Last-Modified only reflects changes to the listed products; the ETag
+	// also catches products entering or leaving the page.
+	respondWithCacheableJSON(w, r, products, latest(products, func(p *service.ProductResponse) time.Time { return p.UpdatedAt }))
+}
+
+// GetProductByID handles GET /api/v1/catalog/products/{id}
+func (h *CatalogHandler) GetProductByID(w http.ResponseWriter, r *http.Request) {
+	product, err := h.catalogService.GetProduct(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, repository.ErrProductNotFound) {
+		jsonutil.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve product")
+		return
+	}
+
+	respondWithCacheableJSON(w, r, product, product.UpdatedAt)
 }
 
 func (h *CatalogHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
@@ -44,5 +66,5 @@ func (h *CatalogHandler) ListCategories(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	jsonutil.RespondWithJSON(w, http.StatusOK, categories)
+	respondWithCacheableJSON(w, r, categories, latest(categories, func(c *service.CategoryResponse) time.Time { return c.UpdatedAt }))
 }
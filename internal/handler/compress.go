@@ -0,0 +1,188 @@
+// backend/internal/handler/compress.go
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressibleTypes are the media types Compress encodes. Anything else, and
+// any response that already has a Content-Encoding, passes through as is.
+var compressibleTypes = []string{"application/json", "text/html", "text/plain"}
+
+// encodingWriter is what gzip.Writer and brotli.Writer have in common.
+type encodingWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders are the supported content codings in order of preference. The
+// writers are pooled since each one allocates sizeable buffers.
+var encoders = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"br", &sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(nil, 5) }}},
+	{"gzip", &sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}},
+}
+
+// Compress encodes responses with brotli or gzip, whichever the client's
+// Accept-Encoding prefers. A compressed response's ETag gets the coding as a
+// suffix, since it is a different representation, and the suffix is removed
+// from If-None-Match again so that handlers can compare the tags they compute.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if tags := r.Header.Values("If-None-Match"); len(tags) > 0 {
+			r.Header.Del("If-None-Match")
+			for _, tag := range tags {
+				r.Header.Add("If-None-Match", stripEncodingSuffixes(tag))
+			}
+		}
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding < 0 || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the index in encoders of the coding the client
+// prefers, or -1 if it accepts none of them.
+func negotiateEncoding(acceptEncoding string) int {
+	best, bestQ := -1, 0.0
+	wildcard := -1.0
+	qs := make([]float64, len(encoders))
+	for i := range qs {
+		qs[i] = -1
+	}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			wildcard = q
+		}
+		for i, e := range encoders {
+			if name == e.name {
+				qs[i] = q
+			}
+		}
+	}
+	for i, q := range qs {
+		if q < 0 {
+			q = wildcard
+		}
+		// Strictly greater, so ties go to the earlier, preferred coding.
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
+
+// stripEncodingSuffixes undoes tagETag on a list of entity tags.
+func stripEncodingSuffixes(tags string) string {
+	for _, e := range encoders {
+		tags = strings.ReplaceAll(tags, `-`+e.name+`"`, `"`)
+	}
+	return tags
+}
+
+// tagETag appends the content coding to a response's ETag, if it has one.
+func tagETag(h http.Header, encoding string) {
+	if etag := h.Get("ETag"); strings.HasSuffix(etag, `"`) {
+		h.Set("ETag", etag[:len(etag)-1]+"-"+encoding+`"`)
+	}
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    int
+	w           encodingWriter // Set once the response turns out to be compressible
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	name := encoders[cw.encoding].name
+	switch {
+	case code == http.StatusNotModified:
+		// The client holds the compressed representation; confirm its tag.
+		tagETag(h, name)
+	case code >= http.StatusOK && code != http.StatusNoContent && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")):
+		h.Set("Content-Encoding", name)
+		h.Del("Content-Length")
+		tagETag(h, name)
+		cw.w = encoders[cw.encoding].pool.Get().(encodingWriter)
+		cw.w.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.w != nil {
+		return cw.w.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.w != nil {
+		cw.w.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close finishes the encoded stream and returns the writer to its pool.
+func (cw *compressWriter) close() {
+	if cw.w == nil {
+		return
+	}
+	cw.w.Close()
+	cw.w.Reset(nil)
+	encoders[cw.encoding].pool.Put(cw.w)
+	cw.w = nil
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && containsString(compressibleTypes, mediaType)
+}
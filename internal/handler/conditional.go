@@ -0,0 +1,123 @@
+// backend/internal/handler/conditional.go
+package handler
+
+import (
+	"backend/pkg/jsonutil"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// respondWithCacheableJSON sends payload like jsonutil.RespondWithJSON, plus a
+// strong ETag computed from the encoded body and, unless modified is zero, a
+// Last-Modified header. A conditional GET that still matches gets an empty 304.
+func respondWithCacheableJSON(w http.ResponseWriter, r *http.Request, payload interface{}, modified time.Time) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	h := w.Header()
+	h.Set("ETag", etag)
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// notModified evaluates If-None-Match or, only when that is absent,
+// If-Modified-Since, as RFC 9110 section 13.2.2 orders them.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if tags := r.Header.Values("If-None-Match"); len(tags) > 0 {
+		return etagListContains(tags, etag)
+	}
+	since := r.Header.Get("If-Modified-Since")
+	if since == "" || modified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(since)
+	// The header has whole seconds, so compare at that precision.
+	return err == nil && !modified.Truncate(time.Second).After(t)
+}
+
+// etagListContains reports whether any of the comma-separated entity tags in
+// lists is "*" or matches etag. If-None-Match uses the weak comparison, so
+// W/ prefixes are ignored.
+func etagListContains(lists []string, etag string) bool {
+	for _, list := range lists {
+		for _, tag := range strings.Split(list, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// latest returns the most recent of the times f picks from items, or the zero
+// time for an empty list.
+func latest[T any](items []T, f func(T) time.Time) time.Time {
+	var newest time.Time
+	for _, item := range items {
+		if t := f(item); t.After(newest) {
+			newest = t
+		}
+	}
+	return newest
+}
+
+// CacheControl sets the Cache-Control header to value on 200 and 304
+// responses from the routes it wraps, so errors are never cached. An empty
+// value leaves responses alone.
+func CacheControl(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if value == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+		})
+	}
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (cw *cacheControlWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		if code == http.StatusOK || code == http.StatusNotModified {
+			cw.Header().Set("Cache-Control", cw.value)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheControlWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,113 @@
+// backend/internal/handler/cors.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions controls which browser origins may call the API.
+type CORSOptions struct {
+	// AllowedOrigins are exact origins ("https://shop.example.com"), "*" for
+	// any origin, or wildcard subdomains ("https://*.example.com", which does
+	// not match the apex domain itself). Empty disables CORS.
+	AllowedOrigins   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // How long browsers may cache a preflight response
+}
+
+// corsAllowedMethods are the methods the API uses.
+var corsAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORS answers preflight requests itself, before routing, so they never reach
+// Authenticator or AdminOnly, and adds the CORS response headers to actual
+// requests from allowed origins.
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(opts.AllowedOrigins) == 0 {
+			return next
+		}
+		allowedMethods := strings.Join(corsAllowedMethods, ", ")
+		allowedHeaders := strings.Join(opts.AllowedHeaders, ", ")
+		exposedHeaders := strings.Join(opts.ExposedHeaders, ", ")
+		maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			allowed := origin != "" && originAllowed(opts.AllowedOrigins, origin)
+			if allowed {
+				if opts.AllowCredentials || !containsString(opts.AllowedOrigins, "*") {
+					h.Set("Access-Control-Allow-Origin", origin)
+				} else {
+					h.Set("Access-Control-Allow-Origin", "*")
+				}
+				if opts.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if !preflight {
+				if allowed && exposedHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A disallowed preflight still gets a 204, just without the
+			// Allow-* headers, which is what makes the browser refuse.
+			if allowed && containsString(corsAllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+				h.Set("Access-Control-Allow-Methods", allowedMethods)
+				if allowedHeaders != "" {
+					h.Set("Access-Control-Allow-Headers", allowedHeaders)
+				}
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// originAllowed matches origin against the configured list.
+func originAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(pattern, "*")
+		if !ok || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) ||
+			len(origin) <= len(prefix)+len(suffix) {
+			continue
+		}
+		// The wildcard may only stand for subdomain labels, not a path or port.
+		sub := origin[len(prefix) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(sub, "/:@?#") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+// backend/internal/handler/cors_test.go
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestRouter builds the real router with handlers that have no services.
+// Preflights are answered by the CORS middleware before any handler runs.
+func newTestRouter(cors CORSOptions) http.Handler {
+	return NewRouter(
+		NewUserHandler(nil),
+		NewCatalogHandler(nil),
+		NewStoreHandler(nil),
+		NewAdminHandler(nil, nil),
+		NewAuthHandler(nil),
+		NewReadiness(nil),
+		RouterOptions{
+			CORS: cors,
+			SecurityHeaders: SecurityHeadersOptions{
+				HSTSMaxAge:            365 * 24 * time.Hour,
+				HSTSIncludeSubdomains: true,
+				FrameAncestors:        "'none'",
+				ReferrerPolicy:        "strict-origin-when-cross-origin",
+			},
+		},
+	)
+}
+
+var testCORS = CORSOptions{
+	AllowedOrigins:   []string{"https://shop.example.com", "https://*.admin.example.com"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type"},
+	ExposedHeaders:   []string{"X-Request-Id"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func preflight(router http.Handler, path, origin, method string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflightAllowed(t *testing.T) {
+	router := newTestRouter(testCORS)
+
+	tests := []struct {
+		name   string
+		path   string
+		origin string
+		method string
+	}{
+		{"store cart", "/api/v1/store/cart/items", "https://shop.example.com", http.MethodPost},
+		{"store cart item", "/api/v1/store/cart/items/42", "https://shop.example.com", http.MethodDelete},
+		{"admin inventory", "/api/v1/admin/products/42/inventory", "https://eu.admin.example.com", http.MethodPatch},
+		{"admin product", "/api/v1/admin/products", "https://eu.admin.example.com", http.MethodPost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := preflight(router, tt.path, tt.origin, tt.method)
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      tt.origin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Max-Age":           "600",
+			}
+			for header, value := range want {
+				if got := rec.Header().Get(header); got != value {
+					t.Errorf("%s = %q, want %q", header, got, value)
+				}
+			}
+			if vary := rec.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Origin" {
+				t.Errorf("Vary = %q, want it to start with Origin", vary)
+			}
+		})
+	}
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	router := newTestRouter(testCORS)
+
+	tests := []struct {
+		name   string
+		path   string
+		origin string
+		method string
+	}{
+		{"unknown origin on store", "/api/v1/store/cart", "https://evil.example.net", http.MethodGet},
+		{"wildcard does not match apex", "/api/v1/admin/products", "https://admin.example.com", http.MethodPost},
+		{"wildcard does not match other scheme", "/api/v1/admin/products", "http://eu.admin.example.com", http.MethodPost},
+		{"store origin is not an admin origin suffix", "/api/v1/admin/products", "https://shop.example.com.evil.net", http.MethodPost},
+		{"unsupported method", "/api/v1/admin/products", "https://eu.admin.example.com", "TRACE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := preflight(router, tt.path, tt.origin, tt.method)
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "" {
+				t.Errorf("Access-Control-Allow-Methods = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestCORSDisabledWithoutOrigins(t *testing.T) {
+	router := newTestRouter(CORSOptions{})
+
+	rec := preflight(router, "/api/v1/store/cart", "https://shop.example.com", http.MethodGet)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+	if rec.Code == http.StatusNoContent {
+		t.Errorf("preflight was answered although CORS is disabled")
+	}
+}
+
+func TestCORSActualRequestAndSecurityHeaders(t *testing.T) {
+	router := newTestRouter(testCORS)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://shop.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Expose-Headers":    "X-Request-Id",
+		"Strict-Transport-Security":        "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":           "nosniff",
+		"Content-Security-Policy":          "frame-ancestors 'none'",
+		"X-Frame-Options":                  "DENY",
+		"Referrer-Policy":                  "strict-origin-when-cross-origin",
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	router := newTestRouter(CORSOptions{AllowedOrigins: []string{"*"}})
+
+	rec := preflight(router, "/api/v1/store/cart", "https://anything.example.org", http.MethodGet)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+}
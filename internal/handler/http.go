@@ -2,19 +2,23 @@
 package handler
 
 import (
+	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/pkg/jsonutil"
+	"backend/pkg/logging"
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
-	"runtime/debug" // Required for printing stack traces
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 // =============================================================================
-// AUTH MIDDLEWARE (Placeholders)
-// In a real application, you would implement the logic in these functions,
-// likely calling your pkg/auth/jwt.go functions.
+// AUTH MIDDLEWARE
 // =============================================================================
 
 // contextKey is a custom type to avoid key collisions in the context.
@@ -25,25 +29,61 @@ const (
 	roleContextKey = contextKey("userRole")
 )
 
-// Authenticator is a middleware that verifies a user's JWT token.
-// If the token is valid, it extracts the user ID and role and injects them
-// into the request's context for later handlers to use.
-func Authenticator(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// --- Production Logic would go here ---
-		// 1. Get token from "Authorization: Bearer <token>" header.
-		// 2. Call auth.ValidateToken(tokenString).
-		// 3. If valid, extract claims. If not, return 401 Unauthorized.
-		// ------------------------------------
-
-		// For now, we inject a MOCK user for testing purposes.
-		log.Println("[Auth Middleware] MOCK: Injecting user 'mock-user-id-123' with role 'admin'")
-		ctx := context.WithValue(r.Context(), userContextKey, "mock-user-id-123")
-		ctx = context.WithValue(ctx, roleContextKey, "admin") // Change to "customer" to test admin routes
-
-		// Call the next handler in the chain with the new, enriched context.
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// userIDFromContext returns the user ID injected by the Authenticator.
+func userIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userContextKey).(string)
+	return userID, ok && userID != ""
+}
+
+// clientInfo collects the caller's IP (as resolved by middleware.RealIP) and request ID.
+func clientInfo(r *http.Request) service.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return service.ClientInfo{IP: ip, RequestID: middleware.GetReqID(r.Context())}
+}
+
+// Authenticator verifies the "Authorization: Bearer <token>" header and
+// injects the user's ID and current role into the request's context for later
+// handlers to use. Missing, invalid and revoked tokens get a 401.
+func Authenticator(s *service.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				respondUnauthorized(w, "Missing bearer token")
+				return
+			}
+
+			user, err := s.Authenticate(r.Context(), token)
+			if errors.Is(err, service.ErrInvalidToken) {
+				respondUnauthorized(w, "Invalid or expired token")
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Error("authentication failed", slog.Any("error", err))
+				jsonutil.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+
+			// The role is read from the database rather than the token, so a
+			// role change takes effect on the next request.
+			role := "customer"
+			if user.Role != nil && *user.Role != "" {
+				role = *user.Role
+			}
+			setRequestUser(r.Context(), user.ID)
+			ctx := context.WithValue(r.Context(), userContextKey, user.ID)
+			ctx = context.WithValue(ctx, roleContextKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func respondUnauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	jsonutil.RespondWithError(w, http.StatusUnauthorized, msg)
 }
 
 // AdminOnly is an authorization middleware that checks for the 'admin' role.
@@ -53,8 +93,8 @@ func AdminOnly(next http.Handler) http.Handler {
 		role, ok := r.Context().Value(roleContextKey).(string)
 
 		if !ok || role != "admin" {
-			log.Printf("[Auth Middleware] FORBIDDEN: User does not have 'admin' role.")
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			logging.FromContext(r.Context()).Warn("forbidden: user does not have 'admin' role", slog.String("role", role))
+			jsonutil.RespondWithError(w, http.StatusForbidden, "Forbidden")
 			return
 		}
 
@@ -63,10 +103,82 @@ func AdminOnly(next http.Handler) http.Handler {
 	})
 }
 
+// serviceErrors maps the sentinel errors of the repositories and services
+// onto statuses and client-facing messages.
+var serviceErrors = []struct {
+	err    error
+	status int
+	msg    string
+}{
+	{repository.ErrProductNotFound, http.StatusNotFound, "Product not found"},
+	{repository.ErrVariantNotFound, http.StatusNotFound, "Variant not found"},
+	{repository.ErrUserNotFound, http.StatusNotFound, "User not found"},
+	{repository.ErrRoleNotFound, http.StatusNotFound, "Role not found"},
+	{repository.ErrPriceChangeNotFound, http.StatusNotFound, "Scheduled price change not found"},
+	{repository.ErrDuplicateSKU, http.StatusConflict, "SKU already in use"},
+	{repository.ErrDuplicateVariant, http.StatusConflict, "A variant with these options already exists"},
+	{repository.ErrMultipleVariants, http.StatusConflict, "Product has several variants; adjust the inventory of one of them"},
+	{service.ErrLastVariant, http.StatusConflict, "A product must keep at least one variant; delete the product instead"},
+}
+
+// respondWithServiceError is the fallback for handlers without special cases:
+// validation errors become a 422 and known sentinel errors their status.
+// Anything else is logged and sent as a generic 500, so database and driver
+// errors never reach clients.
+func respondWithServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		respondWithValidationError(w, verr)
+		return
+	}
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.err) {
+			jsonutil.RespondWithError(w, e.status, e.msg)
+			return
+		}
+	}
+	logging.FromContext(r.Context()).Error("request failed", slog.Any("error", err))
+	jsonutil.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+}
+
+// respondWithValidationError sends a 422 with a message per invalid field.
+func respondWithValidationError(w http.ResponseWriter, verr *service.ValidationError) {
+	jsonutil.RespondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"error":  "Validation failed",
+		"fields": verr.Fields,
+	})
+}
+
 // =============================================================================
 // MAIN ROUTER SETUP
 // =============================================================================
 
+// RouterOptions holds settings and shared dependencies for the router.
+type RouterOptions struct {
+	Logger              *slog.Logger
+	RegistrationEnabled bool
+	// Metrics serves /metrics when set; request metrics are recorded either way.
+	Metrics http.Handler
+	// RateLimiter enforces RateLimits when set.
+	RateLimiter     *service.RateLimitService
+	RateLimits      RateLimitPolicies
+	CORS            CORSOptions
+	SecurityHeaders SecurityHeadersOptions
+	// APIDocs serves a browsable rendering of the OpenAPI document at /api/docs.
+	APIDocs bool
+	// Compression gzip- or brotli-encodes JSON, HTML and text responses.
+	Compression  bool
+	CacheControl CacheControlPolicies
+}
+
+// CacheControlPolicies are the Cache-Control values for successful catalog
+// responses, per route. Empty values send no header.
+type CacheControlPolicies struct {
+	Products   string // Product listings
+	Product    string // A single product
+	Categories string
+}
+
 // NewRouter creates and configures the main application router.
 // It accepts all the handlers for dependency injection.
 func NewRouter(
@@ -74,71 +186,123 @@ func NewRouter(
 	catalogHandler *CatalogHandler,
 	storeHandler *StoreHandler,
 	adminHandler *AdminHandler,
+	authHandler *AuthHandler,
+	readiness *Readiness,
+	opts RouterOptions,
 ) http.Handler {
 	r := chi.NewRouter()
+	authenticate := Authenticator(userHandler.userService)
 
 	// --- Standard Middleware ---
 
-	// Recoverer catches panics and prevents the server from crashing.
-	// This custom implementation also logs the stack trace for debugging.
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			defer func() {
-				if rvr := recover(); rvr != nil {
-					log.Printf("--- PANIC RECOVERED ---")
-					log.Printf("Panic: %+v", rvr)
-					// Print the stack trace to the console
-					log.Printf("Stack trace:\n%s", debug.Stack())
-					// Return a generic 500 error to the client
-					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				}
-			}()
-			next.ServeHTTP(w, r)
-		})
-	})
+	r.Use(middleware.RequestID)       // Injects a request ID into the context of each request.
+	r.Use(middleware.RealIP)          // Sets a http.Request's RemoteAddr to either X-Real-IP or X-Forwarded-For.
+	r.Use(Tracing)                    // OpenTelemetry server span, continuing any incoming traceparent.
+	r.Use(RequestLogger(opts.Logger)) // Request-scoped structured logger plus one access log line per request.
+	r.Use(Metrics)                    // Request counts and latencies by route pattern and status.
+	r.Use(Recoverer)                  // Logs panics as a single event and returns a JSON 500.
+	r.Use(SecurityHeaders(opts.SecurityHeaders))
+	r.Use(CORS(opts.CORS))               // Answers preflights before routing, so they skip auth in the groups below.
+	r.Use(middleware.Heartbeat("/ping")) // Legacy health-check endpoint; prefer /healthz.
+	if opts.Compression {
+		r.Use(Compress)
+	}
+
+	// Liveness (process is up) and readiness (dependencies are up and we're not draining).
+	r.Get("/healthz", Liveness)
+	r.Method(http.MethodGet, "/readyz", readiness)
+
+	// Prometheus scrape endpoint.
+	if opts.Metrics != nil {
+		r.Method(http.MethodGet, "/metrics", opts.Metrics)
+	}
 
-	r.Use(middleware.RequestID) // Injects a request ID into the context of each request.
-	r.Use(middleware.RealIP)    // Sets a http.Request's RemoteAddr to either X-Real-IP or X-Forwarded-For.
-	r.Use(middleware.Logger)    // Logs the start and end of each request with structured data.
-	r.Use(middleware.Heartbeat("/ping")) // A health-check endpoint.
+	// Public keys for verifying our JWTs, at the conventional location.
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
+
+	// OpenAPI description of everything registered here; see openapi.go.
+	r.Get("/api/openapi.json", OpenAPI(NewOpenAPIDocument(opts)))
+	if opts.APIDocs {
+		r.Get("/api/docs", APIDocs)
+	}
 
 	// --- API Route Grouping ---
 	r.Route("/api/v1", func(r chi.Router) {
 
 		// == Group 1: Public Routes (No Auth Required) ==
-		r.Post("/users/register", userHandler.CreateUser)
-		// r.Post("/users/login", userHandler.Login) // Your future login endpoint
-		r.Get("/catalog/products", catalogHandler.ListProducts)
-		r.Get("/catalog/categories", catalogHandler.ListCategories)
-		// r.Get("/catalog/products/{id}", catalogHandler.GetProductByID)
+		// Account and login endpoints get the strictest rate limit.
+		r.Group(func(r chi.Router) {
+			r.Use(RateLimit(opts.RateLimiter, opts.RateLimits.Auth))
+
+			if opts.RegistrationEnabled {
+				r.Post("/users/register", userHandler.CreateUser)
+			}
+			r.Post("/users/verify-email/request", userHandler.RequestEmailVerification)
+			r.Post("/users/verify-email", userHandler.VerifyEmail)
+			r.Post("/users/password-reset/request", userHandler.RequestPasswordReset)
+			r.Post("/users/password-reset", userHandler.ResetPassword)
+			r.Post("/users/login", authHandler.Login)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(RateLimit(opts.RateLimiter, opts.RateLimits.Catalog))
+
+			r.With(CacheControl(opts.CacheControl.Products)).Get("/catalog/products", catalogHandler.ListProducts)
+			r.With(CacheControl(opts.CacheControl.Product)).Get("/catalog/products/{id}", catalogHandler.GetProductByID)
+			r.With(CacheControl(opts.CacheControl.Categories)).Get("/catalog/categories", catalogHandler.ListCategories)
+		})
 
 		// == Group 2: Authenticated Routes (User must be logged in) ==
 		r.Group(func(r chi.Router) {
-			r.Use(Authenticator) // This middleware protects all routes inside this group.
+			r.Use(authenticate) // This middleware protects all routes inside this group.
+			// Rate limited per user rather than per IP, since Authenticator has run.
+			r.Use(RateLimit(opts.RateLimiter, opts.RateLimits.API))
 
 			// User-specific profile routes
-			// r.Get("/users/me", userHandler.GetMyProfile)
+			r.Route("/users/me", func(r chi.Router) {
+				r.Get("/", userHandler.GetMyProfile)
+				r.Patch("/", userHandler.UpdateMyProfile)
+				r.Delete("/", userHandler.DeleteMyAccount)
+				r.Post("/password", userHandler.ChangeMyPassword)
+			})
 
 			// Store routes (cart, orders, wishlist)
 			r.Route("/store", func(r chi.Router) {
 				r.Get("/cart", storeHandler.GetCart)
 				r.Post("/cart/items", storeHandler.AddToCart)
-				r.Delete("/cart/items/{productID}", storeHandler.RemoveFromCart)
+				r.Delete("/cart/items/{variantID}", storeHandler.RemoveFromCart)
 			})
 		})
 
 		// == Group 3: Admin Routes (User must be logged in AND have 'admin' role) ==
 		r.Group(func(r chi.Router) {
-			r.Use(Authenticator) // First, verify they are a valid user.
-			r.Use(AdminOnly)     // Then, check if they have admin permissions.
+			r.Use(authenticate) // First, verify they are a valid user.
+			r.Use(AdminOnly)    // Then, check if they have admin permissions.
+			r.Use(RateLimit(opts.RateLimiter, opts.RateLimits.API))
 
 			r.Route("/admin", func(r chi.Router) {
 				r.Post("/products", adminHandler.CreateProduct)
+				r.Post("/products/bulk", adminHandler.BulkCreateProducts)
+				r.Put("/products/{id}", adminHandler.UpdateProduct)
+				r.Delete("/products/{id}", adminHandler.DeleteProduct)
 				r.Patch("/products/{id}/inventory", adminHandler.AdjustInventory)
-				// Add other admin routes like PUT and DELETE for products here.
+				r.Post("/products/{id}/variants", adminHandler.CreateVariant)
+				r.Put("/variants/{id}", adminHandler.UpdateVariant)
+				r.Delete("/variants/{id}", adminHandler.DeleteVariant)
+				r.Patch("/variants/{id}/inventory", adminHandler.AdjustVariantInventory)
+				r.Put("/variants/{id}/price", adminHandler.SetPrice)
+				r.Post("/variants/{id}/price-changes", adminHandler.SchedulePriceChange)
+				r.Get("/variants/{id}/price-history", adminHandler.PriceHistory)
+				r.Delete("/price-changes/{id}", adminHandler.CancelPriceChange)
+				r.Put("/variants/{id}/sale", adminHandler.SetSale)
+				r.Delete("/variants/{id}/sale", adminHandler.EndSale)
+				r.Get("/users/{id}", userHandler.GetUserByID)
+				r.Put("/users/{id}/role", adminHandler.ChangeUserRole)
+				r.Post("/users/{id}/unlock", authHandler.UnlockAccount)
+				r.Get("/audit", adminHandler.ListAuditLog)
 			})
 		})
 	})
 
 	return r
-}
\ No newline at end of file
+}
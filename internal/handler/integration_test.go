@@ -0,0 +1,400 @@
+// backend/internal/handler/integration_test.go
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/handler"
+	"backend/internal/pgtest"
+	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/pkg/auth"
+	"backend/pkg/cache"
+	"backend/pkg/database"
+	"backend/pkg/health"
+	"backend/pkg/mailer"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// End-to-end tests: the real router, services and Postgres repositories,
+// each test against its own empty schema (see package pgtest).
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+// outbox keeps the emails the app sends.
+type outbox struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+var tokenLink = regexp.MustCompile(`token=(\S+)`)
+
+func (o *outbox) lastToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.sent) == 0 {
+		t.Fatal("no email was sent")
+	}
+	match := tokenLink.FindStringSubmatch(o.sent[len(o.sent)-1].Body)
+	if match == nil {
+		t.Fatal("the last email has no token link")
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+type testApp struct {
+	server *httptest.Server
+	pool   *pgxpool.Pool
+	mail   *outbox
+	token  string // Sent as a bearer token when set
+}
+
+// newTestApp wires the application the way cmd/server does, minus config.
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	pool := pgtest.New(t)
+
+	key, err := auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{SigningKey: key, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := service.NewPasswordPolicy(12, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewPostgresUserRepository(pool)
+	logRepo := repository.NewPostgresLogRepository(pool)
+	txManager := repository.NewPostgresTxManager(pool)
+	mail := &outbox{}
+
+	userService := service.NewUserService(userRepo, repository.NewPostgresUserTokenRepository(pool), txManager, mail, service.UserServiceOptions{
+		AppBaseURL:     "https://shop.example.com",
+		BcryptCost:     bcrypt.MinCost,
+		PasswordPolicy: policy,
+		Tokens:         tokens,
+	})
+	authService := service.NewAuthService(userRepo, repository.NewPostgresLoginAttemptRepository(pool), logRepo, tokens, service.LockoutPolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		Window:             15 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+	})
+
+	catalogCache := service.NewCatalogCache(cache.NewLRU(100), time.Minute)
+	adminRepo := repository.NewPostgresAdminRepository(pool)
+	productRepo := repository.NewPostgresProductRepository(pool)
+
+	checks := health.NewRegistry()
+	checks.Register("database", true, 2*time.Second, database.HealthCheck(pool))
+
+	router := handler.NewRouter(
+		handler.NewUserHandler(userService),
+		handler.NewCatalogHandler(service.NewCatalogService(productRepo, catalogCache)),
+		handler.NewStoreHandler(service.NewStoreService(repository.NewPostgresStoreRepository(pool))),
+		handler.NewAdminHandler(
+			service.NewAdminService(adminRepo, logRepo, txManager, catalogCache),
+			service.NewPricingService(adminRepo, productRepo, repository.NewPostgresPriceRepository(pool), logRepo, txManager, catalogCache),
+		),
+		handler.NewAuthHandler(authService),
+		handler.NewReadiness(checks),
+		handler.RouterOptions{RegistrationEnabled: true},
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &testApp{server: server, pool: pool, mail: mail}
+}
+
+// do sends body as JSON and decodes a JSON response into out, if given.
+func (a *testApp) do(t *testing.T, method, path string, body, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, a.server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	res, err := a.server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return res.StatusCode
+}
+
+// login logs in and returns the token.
+func (a *testApp) login(t *testing.T, email, password string) string {
+	t.Helper()
+	var res service.LoginResponse
+	if code := a.do(t, "POST", "/api/v1/users/login", service.LoginRequest{Email: email, Password: password}, &res); code != http.StatusOK {
+		t.Fatalf("login as %s = %d", email, code)
+	}
+	return res.Token
+}
+
+func TestE2EReadiness(t *testing.T) {
+	app := newTestApp(t)
+	if code := app.do(t, "GET", "/readyz", nil, nil); code != http.StatusOK {
+		t.Errorf("GET /readyz = %d, want 200", code)
+	}
+}
+
+func TestE2ECatalog(t *testing.T) {
+	app := newTestApp(t)
+	drinks := pgtest.SeedCategory(t, app.pool, "Drinks")
+	pgtest.SeedCategory(t, app.pool, "Bakery")
+	pgtest.SeedProduct(t, app.pool, drinks, "Coffee", 12.5, 10)
+	pgtest.SeedProduct(t, app.pool, "", "Tea", 4.25, 0)
+
+	var products []service.ProductResponse
+	if code := app.do(t, "GET", "/api/v1/catalog/products?limit=10", nil, &products); code != http.StatusOK {
+		t.Fatalf("GET products = %d", code)
+	}
+	if len(products) != 2 {
+		t.Fatalf("got %d products, want 2", len(products))
+	}
+	for _, p := range products {
+		if p.Name == "Coffee" && (p.CategoryID == nil || *p.CategoryID != drinks || p.PriceRange == nil || p.PriceRange.Min != 12.5 || len(p.Variants) != 1) {
+			t.Errorf("coffee = %+v", p)
+		}
+	}
+
+	var categories []service.CategoryResponse
+	if code := app.do(t, "GET", "/api/v1/catalog/categories", nil, &categories); code != http.StatusOK {
+		t.Fatalf("GET categories = %d", code)
+	}
+	if len(categories) != 2 || categories[0].Name != "Bakery" {
+		t.Errorf("categories = %+v, want Bakery first", categories)
+	}
+}
+
+func TestE2ERegisterVerifyAndLogin(t *testing.T) {
+	app := newTestApp(t)
+	const password = "correct horse battery"
+
+	var user service.UserResponse
+	code := app.do(t, "POST", "/api/v1/users/register", service.CreateUserRequest{FullName: "Jane Doe", Email: "Jane@Example.com", Password: password}, &user)
+	if code != http.StatusCreated {
+		t.Fatalf("register = %d", code)
+	}
+	if user.Email != "jane@example.com" || user.EmailVerified {
+		t.Errorf("registered user = %+v", user)
+	}
+
+	if code := app.do(t, "POST", "/api/v1/users/login", service.LoginRequest{Email: "jane@example.com", Password: password}, nil); code != http.StatusForbidden {
+		t.Errorf("login before verifying = %d, want 403", code)
+	}
+
+	if code := app.do(t, "POST", "/api/v1/users/verify-email", service.VerifyEmailRequest{Token: app.mail.lastToken(t)}, nil); code != http.StatusNoContent {
+		t.Fatalf("verify-email = %d", code)
+	}
+	var verified bool
+	if err := app.pool.QueryRow(context.Background(), `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, user.ID).Scan(&verified); err != nil {
+		t.Fatal(err)
+	}
+	if !verified {
+		t.Error("email_verified_at was not set")
+	}
+
+	var login service.LoginResponse
+	if code := app.do(t, "POST", "/api/v1/users/login", service.LoginRequest{Email: "jane@example.com", Password: password}, &login); code != http.StatusOK {
+		t.Fatalf("login = %d", code)
+	}
+	if login.Token == "" || login.User == nil || login.User.ID != user.ID {
+		t.Errorf("login response = %+v", login)
+	}
+
+	if code := app.do(t, "POST", "/api/v1/users/login", service.LoginRequest{Email: "jane@example.com", Password: "wrong password"}, nil); code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password = %d, want 401", code)
+	}
+}
+
+func TestE2ELoginSeededAdmin(t *testing.T) {
+	app := newTestApp(t)
+	pgtest.SeedUser(t, app.pool, "admin@example.com", "admin passphrase", "admin")
+
+	var login service.LoginResponse
+	if code := app.do(t, "POST", "/api/v1/users/login", service.LoginRequest{Email: "admin@example.com", Password: "admin passphrase"}, &login); code != http.StatusOK {
+		t.Fatalf("login = %d", code)
+	}
+	if login.User == nil || login.User.Role == nil || *login.User.Role != "admin" || !login.User.EmailVerified {
+		t.Errorf("seeded admin = %+v", login.User)
+	}
+}
+
+func TestE2EChangePasswordRevokesTokens(t *testing.T) {
+	app := newTestApp(t)
+	pgtest.SeedUser(t, app.pool, "jane@example.com", "correct horse battery", "customer")
+	old := app.login(t, "jane@example.com", "correct horse battery")
+
+	app.token = old
+	var changed struct {
+		Token string `json:"token"`
+	}
+	req := service.ChangePasswordRequest{CurrentPassword: "correct horse battery", NewPassword: "battery staple horse"}
+	if code := app.do(t, "POST", "/api/v1/users/me/password", req, &changed); code != http.StatusOK || changed.Token == "" {
+		t.Fatalf("change password = %d with token %q", code, changed.Token)
+	}
+
+	// Replaying the token issued before the change must fail.
+	if code := app.do(t, "GET", "/api/v1/users/me", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /users/me with the old token = %d, want 401", code)
+	}
+	app.token = changed.Token
+	if code := app.do(t, "GET", "/api/v1/users/me", nil, nil); code != http.StatusOK {
+		t.Errorf("GET /users/me with the new token = %d, want 200", code)
+	}
+	app.token = ""
+	if code := app.do(t, "GET", "/api/v1/users/me", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /users/me without a token = %d, want 401", code)
+	}
+}
+
+func TestE2ECart(t *testing.T) {
+	app := newTestApp(t)
+	pgtest.SeedUser(t, app.pool, "jane@example.com", "correct horse battery", "customer")
+	coffee := pgtest.SeedProduct(t, app.pool, "", "Coffee", 12.5, 10)
+	var variant string
+	if err := app.pool.QueryRow(context.Background(), `SELECT id FROM product_variants WHERE product_id = $1`, coffee).Scan(&variant); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := app.do(t, "GET", "/api/v1/store/cart", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("GET cart without a token = %d, want 401", code)
+	}
+	app.token = app.login(t, "jane@example.com", "correct horse battery")
+
+	if code := app.do(t, "POST", "/api/v1/store/cart/items", service.AddItemToCartRequest{VariantID: variant, Quantity: 2}, nil); code != http.StatusNoContent {
+		t.Fatalf("add to cart = %d, want 204", code)
+	}
+	missing := service.AddItemToCartRequest{VariantID: "00000000-0000-4000-8000-000000000000", Quantity: 1}
+	if code := app.do(t, "POST", "/api/v1/store/cart/items", missing, nil); code != http.StatusNotFound {
+		t.Errorf("adding a missing variant = %d, want 404", code)
+	}
+
+	var cart service.CartResponse
+	if code := app.do(t, "GET", "/api/v1/store/cart", nil, &cart); code != http.StatusOK {
+		t.Fatalf("GET cart = %d", code)
+	}
+	if len(cart.Items) != 1 || cart.TotalItems != 2 || cart.TotalPrice != 25 {
+		t.Errorf("cart = %+v, want two coffees for 25", cart)
+	}
+
+	if code := app.do(t, "DELETE", "/api/v1/store/cart/items/not-a-uuid", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("removing a malformed variant ID = %d, want 400", code)
+	}
+	if code := app.do(t, "DELETE", "/api/v1/store/cart/items/"+variant, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("remove from cart = %d, want 204", code)
+	}
+	if code := app.do(t, "GET", "/api/v1/store/cart", nil, &cart); code != http.StatusOK || len(cart.Items) != 0 {
+		t.Errorf("GET cart after removing = %d with %d items, want 200 with none", code, len(cart.Items))
+	}
+}
+
+func TestE2EAdminRequiresAdminRole(t *testing.T) {
+	app := newTestApp(t)
+	pgtest.SeedUser(t, app.pool, "jane@example.com", "correct horse battery", "customer")
+	app.token = app.login(t, "jane@example.com", "correct horse battery")
+
+	if code := app.do(t, "POST", "/api/v1/admin/products", service.CreateProductRequest{Name: "Tea", Price: 4.25}, nil); code != http.StatusForbidden {
+		t.Errorf("create product as a customer = %d, want 403", code)
+	}
+}
+
+func TestE2EAdminProducts(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	pgtest.SeedUser(t, app.pool, "admin@example.com", "admin passphrase", "admin")
+	app.token = app.login(t, "admin@example.com", "admin passphrase")
+	coffee := pgtest.SeedProduct(t, app.pool, "", "Coffee", 12.5, 10)
+
+	// Fill the catalog cache, which the writes below must invalidate.
+	var listed []service.ProductResponse
+	if code := app.do(t, "GET", "/api/v1/catalog/products", nil, &listed); code != http.StatusOK || len(listed) != 1 {
+		t.Fatalf("GET products = %d with %d products, want 200 with 1", code, len(listed))
+	}
+
+	var adjusted struct {
+		NewInventoryCount int `json:"new_inventory_count"`
+	}
+	code := app.do(t, "PATCH", "/api/v1/admin/products/"+coffee+"/inventory", service.AdjustInventoryRequest{Change: -3}, &adjusted)
+	if code != http.StatusOK || adjusted.NewInventoryCount != 7 {
+		t.Fatalf("adjust inventory = %d, %d; want 200, 7", code, adjusted.NewInventoryCount)
+	}
+	missing := "/api/v1/admin/products/00000000-0000-4000-8000-000000000000/inventory"
+	if code := app.do(t, "PATCH", missing, service.AdjustInventoryRequest{Change: 1}, nil); code != http.StatusNotFound {
+		t.Errorf("adjusting a missing product = %d, want 404", code)
+	}
+
+	if code := app.do(t, "POST", "/api/v1/admin/products", service.CreateProductRequest{Name: "Tea", Price: 4.25}, nil); code != http.StatusCreated {
+		t.Errorf("create product = %d, want 201", code)
+	}
+	bulk := service.BulkCreateProductsRequest{Products: []service.CreateProductRequest{{Name: "A", Price: 1}, {Name: "B", Price: 2}}}
+	var created struct {
+		Created int64 `json:"created"`
+	}
+	if code := app.do(t, "POST", "/api/v1/admin/products/bulk", bulk, &created); code != http.StatusCreated || created.Created != 2 {
+		t.Errorf("bulk create = %d, %d; want 201, 2", code, created.Created)
+	}
+
+	var products, audited int
+	if err := app.pool.QueryRow(ctx, `SELECT COUNT(*) FROM products`).Scan(&products); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log WHERE action LIKE 'product.%'`).Scan(&audited); err != nil {
+		t.Fatal(err)
+	}
+	if products != 4 || audited != 3 {
+		t.Errorf("%d products and %d audit entries, want 4 and 3", products, audited)
+	}
+
+	if code := app.do(t, "GET", "/api/v1/catalog/products", nil, &listed); code != http.StatusOK || len(listed) != 4 {
+		t.Errorf("GET products after the writes = %d with %d products, want 200 with 4", code, len(listed))
+	}
+}
@@ -0,0 +1,113 @@
+// backend/internal/handler/logging.go
+package handler
+
+import (
+	"backend/pkg/jsonutil"
+	"backend/pkg/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestLogState lets inner middleware (e.g. Authenticator) add fields to
+// the access log line written by RequestLogger once the request completes.
+type requestLogState struct {
+	userID string
+}
+
+type logStateKey struct{}
+
+// setRequestUser records the authenticated user for the access log.
+func setRequestUser(ctx context.Context, userID string) {
+	if state, ok := ctx.Value(logStateKey{}).(*requestLogState); ok {
+		state.userID = userID
+	}
+}
+
+// RequestLogger attaches a logger carrying the request ID to the request
+// context and writes one access log record per request. It must run after
+// middleware.RequestID, middleware.RealIP and Tracing. A nil base uses slog.Default().
+func RequestLogger(base *slog.Logger) func(http.Handler) http.Handler {
+	if base == nil {
+		base = slog.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			logger := base.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				logger = logger.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+			state := &requestLogState{}
+			ctx := logging.WithLogger(r.Context(), logger)
+			ctx = context.WithValue(ctx, logStateKey{}, state)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK // Handler wrote nothing; net/http sends 200
+				}
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("route", routePattern(r)),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+					slog.String("remote_ip", clientInfo(r).IP),
+				}
+				if state.userID != "" {
+					attrs = append(attrs, slog.String("user_id", state.userID))
+				}
+				level := slog.LevelInfo
+				if status >= http.StatusInternalServerError {
+					level = slog.LevelError
+				}
+				logger.LogAttrs(r.Context(), level, "request completed", attrs...)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// Recoverer catches panics and prevents the server from crashing. The panic
+// and its stack trace are logged as a single record and the client gets a
+// JSON 500. It must run inside RequestLogger so the record carries the request ID.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					panic(rvr) // Deliberate abort; let net/http handle it
+				}
+				logging.FromContext(r.Context()).Error("panic recovered",
+					slog.String("panic", fmt.Sprint(rvr)),
+					slog.String("route", routePattern(r)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				jsonutil.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routePattern returns the matched chi route (e.g. "/api/v1/admin/products/{id}/inventory"),
+// which unlike the raw path is safe to aggregate on.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
@@ -0,0 +1,49 @@
+// backend/internal/handler/metrics.go
+package handler
+
+import (
+	"backend/pkg/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec(
+		"http_requests_total",
+		"Number of HTTP requests, by route pattern and status.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTP request latency, by route pattern and status.",
+		nil,
+		"method", "route", "status",
+	)
+)
+
+func init() {
+	metrics.MustRegister(httpRequestsTotal, httpRequestDuration)
+}
+
+// Metrics records request counts and latencies. Requests are labelled with
+// the chi route pattern rather than the raw path, so IDs in URLs don't create
+// a new series per resource; unmatched paths share the "unmatched" label.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			labels := []string{r.Method, routePattern(r), strconv.Itoa(status)}
+			httpRequestsTotal.WithLabelValues(labels...).Inc()
+			httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
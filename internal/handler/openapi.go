@@ -0,0 +1,380 @@
+// backend/internal/handler/openapi.go
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/auth"
+	"backend/pkg/health"
+	"backend/pkg/openapi"
+)
+
+// access is who may call a route, which decides its security requirement and
+// the auth error responses it can produce.
+type access int
+
+const (
+	public access = iota
+	authenticated
+	adminOnly
+)
+
+// apiRoute documents one route registered in NewRouter. Keep the two in sync;
+// TestOpenAPICoversRouter fails when they drift apart.
+type apiRoute struct {
+	method, path     string
+	id, tag, summary string
+	description      string
+	access           access
+	rateLimited      bool
+	conditional      bool // Sends validators and answers conditional GETs with a 304
+	query            []*openapi.Parameter
+	request          interface{} // Request body DTO, nil for none
+	status           int
+	response         interface{} // Response body, nil for none
+	contentType      string      // Defaults to application/json
+	errors           []int       // Route-specific errors; body, auth and rate limit errors are added automatically
+}
+
+// Doc-only shapes of the bodies written by jsonutil and respondWithValidationError.
+type (
+	errorResponse struct {
+		Error string `json:"error"`
+		// Field is the offending JSON field, for malformed bodies.
+		Field string `json:"field,omitempty"`
+	}
+	validationErrorResponse struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	statusResponse struct {
+		Status string `json:"status"`
+	}
+	tokenResponse struct {
+		Token string `json:"token"`
+	}
+	bulkCreateResponse struct {
+		Created int64 `json:"created"`
+	}
+	inventoryResponse struct {
+		NewInventoryCount int `json:"new_inventory_count"`
+	}
+)
+
+const bearerScheme = "bearerAuth"
+
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:            "Malformed request body or rejected request",
+	http.StatusUnauthorized:          "Missing or invalid credentials",
+	http.StatusForbidden:             "Not allowed",
+	http.StatusNotFound:              "Not found",
+	http.StatusConflict:              "Conflicts with the resource's current state",
+	http.StatusRequestEntityTooLarge: "Request body too large",
+	http.StatusUnsupportedMediaType:  "Content-Type is not application/json",
+	http.StatusUnprocessableEntity:   "Validation failed",
+	http.StatusTooManyRequests:       "Rate limit exceeded",
+	http.StatusInternalServerError:   "Internal error",
+	http.StatusServiceUnavailable:    "Not ready to serve traffic",
+}
+
+// apiRoutes lists the routes NewRouter registers for opts.
+func apiRoutes(opts RouterOptions) []apiRoute {
+	limited := opts.RateLimiter != nil
+	pagination := []*openapi.Parameter{
+		{Name: "page", In: "query", Description: "1-based page number.", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1), Default: 1}},
+		{Name: "limit", In: "query", Description: "Page size; out-of-range values fall back to the default.", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(100), Default: 20}},
+	}
+
+	timestamp := &openapi.Schema{Type: "string", Format: "date-time"}
+	adminLimit := &openapi.Parameter{Name: "limit", In: "query", Description: "Page size; out-of-range values fall back to the default.", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(200), Default: 50}}
+	auditQuery := []*openapi.Parameter{
+		{Name: "actor_id", In: "query", Description: "Only entries by this user.", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "entity_type", In: "query", Description: "e.g. product or user.", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "entity_id", In: "query", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "from", In: "query", Description: "Entries at or after this time.", Schema: timestamp},
+		{Name: "to", In: "query", Description: "Entries before this time.", Schema: timestamp},
+		pagination[0],
+		adminLimit,
+	}
+	historyQuery := []*openapi.Parameter{pagination[0], adminLimit}
+
+	routes := []apiRoute{
+		// Operations
+		{method: "GET", path: "/healthz", id: "liveness", tag: "Operations", summary: "Liveness probe",
+			status: http.StatusOK, response: statusResponse{}},
+		{method: "GET", path: "/readyz", id: "readiness", tag: "Operations", summary: "Readiness probe",
+			description: "Fails while the instance is draining or a required dependency is down.",
+			status:      http.StatusOK, response: health.Report{}, errors: []int{http.StatusServiceUnavailable}},
+		{method: "GET", path: "/.well-known/jwks.json", id: "getJWKS", tag: "Auth", summary: "Public keys for verifying access tokens",
+			status: http.StatusOK, response: auth.JWKS{}},
+		{method: "GET", path: "/api/openapi.json", id: "getOpenAPI", tag: "Operations", summary: "This document",
+			status: http.StatusOK, response: map[string]interface{}{}},
+
+		// Auth and account recovery
+		{method: "POST", path: "/api/v1/users/verify-email/request", id: "requestEmailVerification", tag: "Auth", summary: "Email a verification link",
+			description: "Always accepted so the response doesn't reveal whether the address is registered.",
+			rateLimited: limited, request: service.EmailRequest{}, status: http.StatusAccepted, errors: []int{http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/users/verify-email", id: "verifyEmail", tag: "Auth", summary: "Confirm an email address",
+			description: "Accounts can only log in once their email address is verified.",
+			rateLimited: limited, request: service.VerifyEmailRequest{}, status: http.StatusNoContent},
+		{method: "POST", path: "/api/v1/users/password-reset/request", id: "requestPasswordReset", tag: "Auth", summary: "Email a password reset link",
+			description: "Always accepted so the response doesn't reveal whether the address is registered.",
+			rateLimited: limited, request: service.EmailRequest{}, status: http.StatusAccepted, errors: []int{http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/users/password-reset", id: "resetPassword", tag: "Auth", summary: "Set a new password with a reset token",
+			rateLimited: limited, request: service.ResetPasswordRequest{}, status: http.StatusNoContent, errors: []int{http.StatusNotFound}},
+		{method: "POST", path: "/api/v1/users/login", id: "login", tag: "Auth", summary: "Exchange credentials for an access token",
+			description: "Repeated failures lock the account or throttle the caller; throttled attempts get a 429 with Retry-After. " +
+				"The email address must be verified first: a correct password for an unverified account gets a 403.",
+			rateLimited: true, request: service.LoginRequest{}, status: http.StatusOK, response: service.LoginResponse{},
+			errors: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError}},
+
+		// Catalog
+		{method: "GET", path: "/api/v1/catalog/products", id: "listProducts", tag: "Catalog", summary: "List products",
+			rateLimited: limited, conditional: true, query: pagination, status: http.StatusOK, response: []*service.ProductResponse{},
+			errors: []int{http.StatusInternalServerError}},
+		{method: "GET", path: "/api/v1/catalog/products/{id}", id: "getProduct", tag: "Catalog", summary: "Get a product",
+			rateLimited: limited, conditional: true, status: http.StatusOK, response: service.ProductResponse{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "GET", path: "/api/v1/catalog/categories", id: "listCategories", tag: "Catalog", summary: "List categories",
+			rateLimited: limited, conditional: true, status: http.StatusOK, response: []*service.CategoryResponse{},
+			errors: []int{http.StatusInternalServerError}},
+
+		// Current user
+		{method: "GET", path: "/api/v1/users/me", id: "getMyProfile", tag: "Users", summary: "Get my profile",
+			access: authenticated, rateLimited: limited, status: http.StatusOK, response: service.UserResponse{}, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "PATCH", path: "/api/v1/users/me", id: "updateMyProfile", tag: "Users", summary: "Update my profile",
+			description: "Omitted fields are left unchanged; an empty phone clears it.",
+			access:      authenticated, rateLimited: limited, request: service.UpdateProfileRequest{}, status: http.StatusOK, response: service.UserResponse{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "DELETE", path: "/api/v1/users/me", id: "deleteMyAccount", tag: "Users", summary: "Delete my account",
+			access: authenticated, rateLimited: limited, request: service.DeleteAccountRequest{}, status: http.StatusNoContent,
+			errors: []int{http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/users/me/password", id: "changeMyPassword", tag: "Users", summary: "Change my password",
+			description: "Signs out other sessions and returns a fresh token for this one.",
+			access:      authenticated, rateLimited: limited, request: service.ChangePasswordRequest{}, status: http.StatusOK, response: tokenResponse{},
+			errors: []int{http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+
+		// Store
+		{method: "GET", path: "/api/v1/store/cart", id: "getCart", tag: "Store", summary: "Get my cart",
+			access: authenticated, rateLimited: limited, status: http.StatusOK, response: service.CartResponse{},
+			errors: []int{http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/store/cart/items", id: "addToCart", tag: "Store", summary: "Add a product variant to my cart",
+			access: authenticated, rateLimited: limited, request: service.AddItemToCartRequest{}, status: http.StatusNoContent,
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "DELETE", path: "/api/v1/store/cart/items/{variantID}", id: "removeFromCart", tag: "Store", summary: "Remove a product variant from my cart",
+			access: authenticated, rateLimited: limited, status: http.StatusNoContent, errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+
+		// Admin
+		{method: "POST", path: "/api/v1/admin/products", id: "createProduct", tag: "Admin", summary: "Create a product",
+			description: "A product with options lists its variants; without options it gets one variant from `sku`, `price` and `inventory_count`.",
+			access:      adminOnly, rateLimited: limited, request: service.CreateProductRequest{}, status: http.StatusCreated, response: models.Product{},
+			errors: []int{http.StatusConflict, http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/admin/products/bulk", id: "bulkCreateProducts", tag: "Admin", summary: "Import up to 1000 products",
+			description: "All products are created or none is. Generated IDs are not returned.",
+			access:      adminOnly, rateLimited: limited, request: service.BulkCreateProductsRequest{}, status: http.StatusCreated, response: bulkCreateResponse{},
+			errors: []int{http.StatusConflict, http.StatusInternalServerError}},
+		{method: "PUT", path: "/api/v1/admin/products/{id}", id: "updateProduct", tag: "Admin", summary: "Replace a product's fields",
+			description: "Prices and stock belong to the variants and are left alone.",
+			access:      adminOnly, rateLimited: limited, request: service.UpdateProductRequest{}, status: http.StatusOK, response: models.Product{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "DELETE", path: "/api/v1/admin/products/{id}", id: "deleteProduct", tag: "Admin", summary: "Delete a product",
+			description: "Also deletes its variants and removes them from every cart.",
+			access:      adminOnly, rateLimited: limited, status: http.StatusNoContent, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "PATCH", path: "/api/v1/admin/products/{id}/inventory", id: "adjustInventory", tag: "Admin", summary: "Adjust a single-variant product's stock",
+			description: "Products with several variants answer 409; adjust a variant instead.",
+			access:      adminOnly, rateLimited: limited, request: service.AdjustInventoryRequest{}, status: http.StatusOK, response: inventoryResponse{},
+			errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/admin/products/{id}/variants", id: "createVariant", tag: "Admin", summary: "Add a variant to a product",
+			access: adminOnly, rateLimited: limited, request: service.VariantRequest{}, status: http.StatusCreated, response: models.ProductVariant{},
+			errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+		{method: "PUT", path: "/api/v1/admin/variants/{id}", id: "updateVariant", tag: "Admin", summary: "Replace a variant's fields",
+			description: "The stock and price are left alone; change them through the inventory and price routes.",
+			access:      adminOnly, rateLimited: limited, request: service.UpdateVariantRequest{}, status: http.StatusOK, response: models.ProductVariant{},
+			errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+		{method: "DELETE", path: "/api/v1/admin/variants/{id}", id: "deleteVariant", tag: "Admin", summary: "Delete a variant",
+			description: "Also removes the variant from every cart. A product's last variant can't be deleted.",
+			access:      adminOnly, rateLimited: limited, status: http.StatusNoContent,
+			errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+		{method: "PATCH", path: "/api/v1/admin/variants/{id}/inventory", id: "adjustVariantInventory", tag: "Admin", summary: "Adjust a variant's stock",
+			access: adminOnly, rateLimited: limited, request: service.AdjustInventoryRequest{}, status: http.StatusOK, response: inventoryResponse{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "PUT", path: "/api/v1/admin/variants/{id}/price", id: "setVariantPrice", tag: "Admin", summary: "Change a variant's regular price",
+			description: "Takes effect right away and is recorded in the price history.",
+			access:      adminOnly, rateLimited: limited, request: service.SetPriceRequest{}, status: http.StatusOK, response: models.ProductVariant{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/admin/variants/{id}/price-changes", id: "schedulePriceChange", tag: "Admin", summary: "Schedule a change of a variant's regular price",
+			description: "The price scheduler applies it once `effective_at` has passed, within one scheduler interval.",
+			access:      adminOnly, rateLimited: limited, request: service.SchedulePriceRequest{}, status: http.StatusCreated, response: models.ScheduledPriceChange{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "GET", path: "/api/v1/admin/variants/{id}/price-history", id: "getPriceHistory", tag: "Admin", summary: "Get a variant's price history",
+			description: "Pending changes come soonest first, the paged history newest first.",
+			access:      adminOnly, rateLimited: limited, query: historyQuery, status: http.StatusOK, response: service.PriceHistoryResponse{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "DELETE", path: "/api/v1/admin/price-changes/{id}", id: "cancelPriceChange", tag: "Admin", summary: "Cancel a scheduled price change",
+			description: "Changes that have been applied can't be cancelled.",
+			access:      adminOnly, rateLimited: limited, status: http.StatusNoContent, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "PUT", path: "/api/v1/admin/variants/{id}/sale", id: "setSale", tag: "Admin", summary: "Put a variant on sale",
+			description: "Replaces any sale the variant has. The sale price must be below the regular price, which the catalog keeps showing next to it.",
+			access:      adminOnly, rateLimited: limited, request: service.SaleRequest{}, status: http.StatusOK, response: models.ProductVariant{},
+			errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "DELETE", path: "/api/v1/admin/variants/{id}/sale", id: "endSale", tag: "Admin", summary: "End a variant's sale",
+			access: adminOnly, rateLimited: limited, status: http.StatusNoContent, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "GET", path: "/api/v1/admin/users/{id}", id: "getUser", tag: "Admin", summary: "Get a user",
+			access: adminOnly, rateLimited: limited, status: http.StatusOK, response: service.UserResponse{}, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "PUT", path: "/api/v1/admin/users/{id}/role", id: "changeUserRole", tag: "Admin", summary: "Change a user's role",
+			description: "Revokes the user's existing tokens if the role changes.",
+			access:      adminOnly, rateLimited: limited, request: service.ChangeRoleRequest{}, status: http.StatusNoContent, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "POST", path: "/api/v1/admin/users/{id}/unlock", id: "unlockAccount", tag: "Admin", summary: "Clear a login lockout",
+			access: adminOnly, rateLimited: limited, status: http.StatusNoContent, errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{method: "GET", path: "/api/v1/admin/audit", id: "listAuditLog", tag: "Admin", summary: "Search the audit log",
+			description: "Entries are newest first. Each admin change records its before and after values under `changes`.",
+			access:      adminOnly, rateLimited: limited, query: auditQuery, status: http.StatusOK, response: []*models.AuditEntry{},
+			errors: []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}},
+	}
+
+	if opts.RegistrationEnabled {
+		routes = append(routes, apiRoute{method: "POST", path: "/api/v1/users/register", id: "register", tag: "Auth", summary: "Create an account",
+			rateLimited: limited, request: service.CreateUserRequest{}, status: http.StatusCreated, response: service.UserResponse{},
+			errors: []int{http.StatusConflict, http.StatusInternalServerError}})
+	}
+	if opts.Metrics != nil {
+		routes = append(routes, apiRoute{method: "GET", path: "/metrics", id: "metrics", tag: "Operations", summary: "Prometheus metrics",
+			status: http.StatusOK, response: "", contentType: "text/plain; version=0.0.4"})
+	}
+	if opts.APIDocs {
+		routes = append(routes, apiRoute{method: "GET", path: "/api/docs", id: "apiDocs", tag: "Operations", summary: "Browsable API documentation",
+			status: http.StatusOK, response: "", contentType: "text/html"})
+	}
+	return routes
+}
+
+// NewOpenAPIDocument describes the API served by NewRouter with opts.
+func NewOpenAPIDocument(opts RouterOptions) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "AminNCo API",
+		Version:     "1.0.0",
+		Description: "Errors are JSON objects with an `error` message; validation failures also list a message per invalid field.",
+	})
+	doc.Components.SecuritySchemes[bearerScheme] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "Access token from POST /api/v1/users/login.",
+	}
+	doc.Tags = []openapi.Tag{
+		{Name: "Auth", Description: "Login, registration and account recovery"},
+		{Name: "Users", Description: "The signed-in user's account"},
+		{Name: "Catalog", Description: "Products and categories"},
+		{Name: "Store", Description: "Shopping cart"},
+		{Name: "Admin", Description: "Back office; requires the admin role"},
+		{Name: "Operations", Description: "Health, metrics and documentation"},
+	}
+
+	for _, route := range apiRoutes(opts) {
+		doc.AddOperation(route.method, route.path, route.operation(doc))
+	}
+	return doc
+}
+
+func (route apiRoute) operation(doc *openapi.Document) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: route.id,
+		Summary:     route.summary,
+		Description: route.description,
+		Tags:        []string{route.tag},
+		Parameters:  route.query,
+		Responses:   make(map[string]*openapi.Response),
+	}
+
+	success := &openapi.Response{Description: http.StatusText(route.status)}
+	if route.response != nil {
+		contentType := route.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]openapi.MediaType{contentType: {Schema: doc.Schema(route.response)}}
+	}
+	op.Responses[strconv.Itoa(route.status)] = success
+
+	if route.conditional {
+		op.Parameters = append(append([]*openapi.Parameter(nil), route.query...),
+			&openapi.Parameter{Name: "If-None-Match", In: "header", Description: "ETags of cached representations; takes precedence over If-Modified-Since.", Schema: &openapi.Schema{Type: "string"}},
+			&openapi.Parameter{Name: "If-Modified-Since", In: "header", Description: "Last-Modified of the cached representation.", Schema: &openapi.Schema{Type: "string"}},
+		)
+		success.Headers = map[string]*openapi.Header{
+			"ETag":          {Description: "Strong validator computed from the body.", Schema: &openapi.Schema{Type: "string"}},
+			"Last-Modified": {Description: "When the most recently updated item changed.", Schema: &openapi.Schema{Type: "string"}},
+			"Cache-Control": {Description: "Configured per route.", Schema: &openapi.Schema{Type: "string"}},
+		}
+		op.Responses[strconv.Itoa(http.StatusNotModified)] = &openapi.Response{Description: "The cached representation is still current"}
+	}
+
+	errs := append([]int(nil), route.errors...)
+	if route.request != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Schema(route.request))}
+		errs = append(errs, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity)
+	}
+	if route.access >= authenticated {
+		op.Security = []openapi.SecurityRequirement{{bearerScheme: {}}}
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	if route.access == adminOnly {
+		errs = append(errs, http.StatusForbidden)
+	}
+	if route.rateLimited {
+		errs = append(errs, http.StatusTooManyRequests)
+	}
+
+	sort.Ints(errs)
+	for _, code := range errs {
+		op.Responses[strconv.Itoa(code)] = errorResponseFor(doc, code)
+	}
+	return op
+}
+
+func errorResponseFor(doc *openapi.Document, code int) *openapi.Response {
+	switch code {
+	case http.StatusUnprocessableEntity:
+		return &openapi.Response{Description: errorDescriptions[code], Content: openapi.JSON(doc.Schema(validationErrorResponse{}))}
+	case http.StatusServiceUnavailable:
+		return &openapi.Response{Description: errorDescriptions[code], Content: openapi.JSON(doc.Schema(health.Report{}))}
+	case http.StatusTooManyRequests:
+		return &openapi.Response{
+			Description: errorDescriptions[code],
+			Headers: map[string]*openapi.Header{
+				"Retry-After": {Description: "Seconds until a request may succeed.", Schema: &openapi.Schema{Type: "integer"}},
+			},
+			Content: openapi.JSON(doc.Schema(errorResponse{})),
+		}
+	}
+	return &openapi.Response{Description: errorDescriptions[code], Content: openapi.JSON(doc.Schema(errorResponse{}))}
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+// OpenAPI serves doc as JSON. The document is encoded once up front.
+func OpenAPI(doc *openapi.Document) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		panic("openapi: encoding document: " + err.Error())
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+//go:embed apidocs.html
+var apiDocsPage []byte
+
+// APIDocs serves a self-contained page that renders /api/openapi.json.
+func APIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(apiDocsPage)
+}
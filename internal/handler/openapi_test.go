@@ -0,0 +1,157 @@
+// backend/internal/handler/openapi_test.go
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"backend/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// allOptions turns on every optional route so the test sees the full API.
+func allOptions() RouterOptions {
+	return RouterOptions{
+		RegistrationEnabled: true,
+		Metrics:             http.NotFoundHandler(),
+		RateLimiter:         &service.RateLimitService{},
+		APIDocs:             true,
+	}
+}
+
+func routerOperations(t *testing.T, opts RouterOptions) []string {
+	t.Helper()
+	router := NewRouter(NewUserHandler(nil), NewCatalogHandler(nil), NewStoreHandler(nil),
+		NewAdminHandler(nil, nil), NewAuthHandler(nil), NewReadiness(nil), opts)
+
+	var ops []string
+	err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// Subrouters mounted with Route register their index as "/prefix/".
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		ops = append(ops, method+" "+route)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking router: %v", err)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func TestOpenAPICoversRouter(t *testing.T) {
+	for name, opts := range map[string]RouterOptions{"all features": allOptions(), "defaults": {}} {
+		t.Run(name, func(t *testing.T) {
+			routes := routerOperations(t, opts)
+			documented := make(map[string]bool)
+			for _, op := range NewOpenAPIDocument(opts).Operations() {
+				documented[op] = true
+			}
+
+			for _, route := range routes {
+				if !documented[route] {
+					t.Errorf("%s is routed but missing from the OpenAPI document; add it to apiRoutes", route)
+				}
+				delete(documented, route)
+			}
+			for op := range documented {
+				t.Errorf("%s is documented but not routed", op)
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	router := NewRouter(NewUserHandler(nil), NewCatalogHandler(nil), NewStoreHandler(nil),
+		NewAdminHandler(nil, nil), NewAuthHandler(nil), NewReadiness(nil), allOptions())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas         map[string]json.RawMessage `json:"schemas"`
+			SecuritySchemes map[string]json.RawMessage `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding document: %v", err)
+	}
+
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q, want 3.1.0", doc.OpenAPI)
+	}
+	for _, name := range []string{"ProductResponse", "CartResponse", "UserResponse", "CreateUserRequest", "AddItemToCartRequest"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("components.schemas is missing %s", name)
+		}
+	}
+	if _, ok := doc.Components.SecuritySchemes[bearerScheme]; !ok {
+		t.Errorf("components.securitySchemes is missing %s", bearerScheme)
+	}
+
+	// Bearer-protected groups must say so; public routes must not.
+	tests := []struct {
+		method, path string
+		secured      bool
+	}{
+		{"get", "/api/v1/store/cart", true},
+		{"patch", "/api/v1/admin/products/{id}/inventory", true},
+		{"get", "/api/v1/users/me", true},
+		{"post", "/api/v1/users/login", false},
+		{"get", "/api/v1/catalog/products", false},
+	}
+	for _, tt := range tests {
+		op := doc.Paths[tt.path][tt.method]
+		if op == nil {
+			t.Errorf("%s %s is missing", tt.method, tt.path)
+			continue
+		}
+		_, secured := op["security"]
+		if secured != tt.secured {
+			t.Errorf("%s %s: secured = %v, want %v", tt.method, tt.path, secured, tt.secured)
+		}
+	}
+}
+
+func TestSchemaFromDTO(t *testing.T) {
+	doc := NewOpenAPIDocument(RouterOptions{})
+
+	req := doc.Components.Schemas["AddItemToCartRequest"]
+	if req == nil {
+		t.Fatal("AddItemToCartRequest schema missing")
+	}
+	if got := req.Properties["variant_id"].Format; got != "uuid" {
+		t.Errorf("variant_id format = %q, want uuid", got)
+	}
+	quantity := req.Properties["quantity"]
+	if quantity.Minimum == nil || *quantity.Minimum != 1 || quantity.Maximum == nil || *quantity.Maximum != 99 {
+		t.Errorf("quantity bounds = %v..%v, want 1..99", quantity.Minimum, quantity.Maximum)
+	}
+	if len(req.Required) != 1 || req.Required[0] != "variant_id" {
+		t.Errorf("required = %v, want [variant_id]", req.Required)
+	}
+
+	user := doc.Components.Schemas["UserResponse"]
+	if phone := user.Properties["phone"]; phone == nil {
+		t.Error("UserResponse.phone missing")
+	} else if types, ok := phone.Type.([]string); !ok || len(types) != 2 || types[1] != "null" {
+		t.Errorf("phone type = %v, want nullable string", phone.Type)
+	}
+	for _, field := range user.Required {
+		if field == "phone" || field == "preferred_language" {
+			t.Errorf("optional field %s is listed as required", field)
+		}
+	}
+}
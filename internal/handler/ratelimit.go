@@ -0,0 +1,67 @@
+// backend/internal/handler/ratelimit.go
+package handler
+
+import (
+	"backend/internal/service"
+	"backend/pkg/jsonutil"
+	"backend/pkg/logging"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitPolicies are the per-group limits: Auth covers registration, login
+// and the email token flows, Catalog the public catalog, and API everything
+// that requires a login.
+type RateLimitPolicies struct {
+	Auth    service.RateLimitPolicy
+	Catalog service.RateLimitPolicy
+	API     service.RateLimitPolicy
+}
+
+// RateLimit enforces policy with a token bucket per client. Requests are keyed
+// by user ID when Authenticator has run earlier in the chain and by client IP
+// (as set by middleware.RealIP) otherwise. A nil limiter disables limiting.
+//
+// If the bucket store fails the request is let through: a broken limiter
+// should not take the API down with it.
+func RateLimit(limiter *service.RateLimitService, policy service.RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + clientInfo(r).IP
+			if userID, ok := userIDFromContext(r.Context()); ok {
+				key = "user:" + userID
+			}
+
+			decision, err := limiter.Allow(r.Context(), policy, key)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limiter unavailable; allowing request",
+					slog.String("policy", policy.Name), slog.Any("error", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Policy", strconv.Itoa(policy.Requests)+";w="+strconv.Itoa(ceilSeconds(policy.Period)))
+			h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+
+			if !decision.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+				jsonutil.RespondWithError(w, http.StatusTooManyRequests, "Too many requests, please retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
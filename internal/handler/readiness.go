@@ -0,0 +1,53 @@
+// backend/internal/handler/readiness.go
+package handler
+
+import (
+	"backend/pkg/health"
+	"backend/pkg/jsonutil"
+	"net/http"
+	"sync/atomic"
+)
+
+// Readiness tracks whether this instance should receive new traffic. It is
+// flipped to draining at the start of a graceful shutdown so load balancers
+// stop routing here before the listener closes; otherwise it is ready when
+// every required check in the registry passes.
+type Readiness struct {
+	draining atomic.Bool
+	checks   *health.Registry
+}
+
+func NewReadiness(checks *health.Registry) *Readiness {
+	return &Readiness{checks: checks}
+}
+
+// StartDraining makes the readiness endpoint fail from now on.
+func (rd *Readiness) StartDraining() {
+	rd.draining.Store(true)
+}
+
+func (rd *Readiness) Draining() bool {
+	return rd.draining.Load()
+}
+
+// ServeHTTP handles GET /readyz
+func (rd *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rd.Draining() {
+		jsonutil.RespondWithJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
+	report := rd.checks.Run(r.Context())
+	code := http.StatusOK
+	if report.Status != health.StatusUp {
+		code = http.StatusServiceUnavailable
+	}
+	jsonutil.RespondWithJSON(w, code, report)
+}
+
+// Liveness handles GET /healthz. It only says the process is serving requests;
+// dependencies are deliberately not checked so an outage elsewhere doesn't get
+// healthy instances restarted.
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	jsonutil.RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
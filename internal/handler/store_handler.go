@@ -4,7 +4,7 @@ package handler
 import (
 	"backend/internal/service"
 	"backend/pkg/jsonutil"
-	"encoding/json"
+	"backend/pkg/validate"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -20,13 +20,15 @@ func NewStoreHandler(s *service.StoreService) *StoreHandler {
 
 // GetCart handles GET /api/v1/store/cart
 func (h *StoreHandler) GetCart(w http.ResponseWriter, r *http.Request) {
-	// NOTE: We assume a middleware has already authenticated the user
-	// and placed their ID in the request context.
-	userID := "user-id-from-auth-middleware" // Placeholder
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 
 	cart, err := h.storeService.GetCart(r.Context(), userID)
 	if err != nil {
-		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve cart")
+		respondWithServiceError(w, r, err)
 		return
 	}
 	jsonutil.RespondWithJSON(w, http.StatusOK, cart)
@@ -34,30 +36,42 @@ func (h *StoreHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 
 // AddToCart handles POST /api/v1/store/cart/items
 func (h *StoreHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
-	userID := "user-id-from-auth-middleware" // Placeholder
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 
 	var req service.AddItemToCartRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
 		return
 	}
 
 	err := h.storeService.AddToCart(r.Context(), userID, req)
 	if err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// RemoveFromCart handles DELETE /api/v1/store/cart/items/{productID}
+// RemoveFromCart handles DELETE /api/v1/store/cart/items/{variantID}
 func (h *StoreHandler) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
-	userID := "user-id-from-auth-middleware" // Placeholder
-	productID := chi.URLParam(r, "productID")
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	variantID := chi.URLParam(r, "variantID")
+	if !validate.UUID(variantID) {
+		jsonutil.RespondWithError(w, http.StatusBadRequest, "Invalid variant ID")
+		return
+	}
 
-	err := h.storeService.RemoveFromCart(r.Context(), userID, productID)
+	err := h.storeService.RemoveFromCart(r.Context(), userID, variantID)
 	if err != nil {
-		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not remove item from cart")
+		respondWithServiceError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -2,9 +2,10 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 
+	"backend/internal/repository"
 	"backend/internal/service"
 	"backend/pkg/jsonutil"
 
@@ -22,16 +23,15 @@ func NewUserHandler(s *service.UserService) *UserHandler {
 // CreateUser handles the POST /api/v1/users request.
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonutil.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
 		return
 	}
 
 	// Call the business logic layer
 	user, err := h.userService.Create(r.Context(), req)
 	if err != nil {
-		// In a real app, you'd check the error type to return different statuses
-		jsonutil.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithUserError(w, r, err)
 		return
 	}
 
@@ -50,3 +50,163 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 
 	jsonutil.RespondWithJSON(w, http.StatusOK, user)
 }
+
+// GetMyProfile handles GET /api/v1/users/me
+func (h *UserHandler) GetMyProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.userService.GetByID(r.Context(), userID)
+	if err != nil {
+		respondWithUserError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// UpdateMyProfile handles PATCH /api/v1/users/me
+func (h *UserHandler) UpdateMyProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req service.UpdateProfileRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(r.Context(), userID, req)
+	if err != nil {
+		respondWithUserError(w, r, err)
+		return
+	}
+
+	jsonutil.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// ChangeMyPassword handles POST /api/v1/users/me/password
+func (h *UserHandler) ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req service.ChangePasswordRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	token, err := h.userService.ChangePassword(r.Context(), userID, req)
+	if err != nil {
+		respondWithUserError(w, r, err)
+		return
+	}
+
+	// Other sessions are now invalid; hand the caller a replacement token.
+	jsonutil.RespondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// DeleteMyAccount handles DELETE /api/v1/users/me
+func (h *UserHandler) DeleteMyAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		jsonutil.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req service.DeleteAccountRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	if err := h.userService.DeleteAccount(r.Context(), userID, req); err != nil {
+		respondWithUserError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestEmailVerification handles POST /api/v1/users/verify-email/request
+func (h *UserHandler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	var req service.EmailRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	if err := h.userService.RequestEmailVerification(r.Context(), req); err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not process request")
+		return
+	}
+	// Always 202 so the response doesn't reveal whether the email is registered.
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifyEmail handles POST /api/v1/users/verify-email
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req service.VerifyEmailRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	if err := h.userService.VerifyEmail(r.Context(), req); err != nil {
+		respondWithUserError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordReset handles POST /api/v1/users/password-reset/request
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req service.EmailRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(r.Context(), req); err != nil {
+		jsonutil.RespondWithError(w, http.StatusInternalServerError, "Could not process request")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /api/v1/users/password-reset
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req service.ResetPasswordRequest
+	if err := jsonutil.Decode(w, r, &req); err != nil {
+		jsonutil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	if err := h.userService.ResetPassword(r.Context(), req); err != nil {
+		respondWithUserError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithUserError maps user service errors onto HTTP statuses.
+func respondWithUserError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmailInUse):
+		jsonutil.RespondWithError(w, http.StatusConflict, "Email already in use")
+	case errors.Is(err, service.ErrInvalidPassword):
+		jsonutil.RespondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repository.ErrTokenInvalid):
+		jsonutil.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithServiceError(w, r, err)
+	}
+}
@@ -0,0 +1,30 @@
+// backend/internal/models/audit.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry corresponds to the append-only "audit_log" table.
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	ActorID    *string         `json:"actor_id,omitempty"` // Nil for system or anonymous events
+	Action     string          `json:"action"`             // e.g. "auth.lockout"
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty"` // {"field": {"before": ..., "after": ...}}
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// LoginThrottle corresponds to the "login_attempts" table. Key identifies
+// what is being throttled, e.g. "account:jane@example.com" or "ip:203.0.113.7".
+type LoginThrottle struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
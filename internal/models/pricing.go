@@ -0,0 +1,62 @@
+// backend/internal/models/pricing.go
+package models
+
+import "time"
+
+// Sale is a temporary price for a variant, stored in the sale_* columns of
+// "product_variants". EndsAt is nil for a sale that runs until it is ended.
+type Sale struct {
+	Price    float64    `json:"price"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// Active reports whether the sale is running at t. A nil sale never is.
+func (s *Sale) Active(t time.Time) bool {
+	return s != nil && !t.Before(s.StartsAt) && (s.EndsAt == nil || t.Before(*s.EndsAt))
+}
+
+// OnSale reports whether the variant sells below its regular price at t. A
+// sale no longer counts once a price change has brought the regular price
+// down to the sale price.
+func (v *ProductVariant) OnSale(t time.Time) bool {
+	return v.Sale.Active(t) && v.Sale.Price < v.Price
+}
+
+// EffectivePrice is what the variant costs at t: the sale price during a
+// sale, the regular price otherwise.
+func (v *ProductVariant) EffectivePrice(t time.Time) float64 {
+	if v.OnSale(t) {
+		return v.Sale.Price
+	}
+	return v.Price
+}
+
+// ScheduledPriceChange corresponds to the "scheduled_price_changes" table.
+type ScheduledPriceChange struct {
+	ID          string     `json:"id"`
+	VariantID   string     `json:"variant_id"`
+	Price       float64    `json:"price"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	CreatedBy   *string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"` // Nil while pending
+}
+
+// Sources of a PriceChange.
+const (
+	PriceSourceAdmin    = "admin"
+	PriceSourceSchedule = "schedule"
+)
+
+// PriceChange corresponds to the append-only "price_history" table.
+type PriceChange struct {
+	ID                string    `json:"id"`
+	VariantID         string    `json:"variant_id"`
+	OldPrice          float64   `json:"old_price"`
+	NewPrice          float64   `json:"new_price"`
+	Source            string    `json:"source"`                        // PriceSourceAdmin or PriceSourceSchedule
+	ChangedBy         *string   `json:"changed_by,omitempty"`          // Nil for scheduled changes
+	ScheduledChangeID *string   `json:"scheduled_change_id,omitempty"` // Set for scheduled changes
+	ChangedAt         time.Time `json:"changed_at"`
+}
@@ -19,34 +19,54 @@ type Category struct {
 	UpdatedAt     time.Time      `json:"updated_at"`
 }
 
-// Product corresponds to the "products" table.
+// Product corresponds to the "products" table. Customers buy its variants,
+// which carry the SKU, price and stock. BadgeEN and BadgeFI are labels such
+// as "New" shown on the product.
 type Product struct {
-	ID             string         `json:"id"`
-	CategoryID     sql.NullString `json:"category_id,omitempty"`
-	Name           string         `json:"name"`
-	Description    sql.NullString `json:"description,omitempty"`
-	Price          float64        `json:"price"`
-	InventoryCount int            `json:"inventory_count"`
-	NameEN         sql.NullString `json:"name_en,omitempty"`
-	NameFI         sql.NullString `json:"name_fi,omitempty"`
-	DescriptionEN  sql.NullString `json:"description_en,omitempty"`
-	DescriptionFI  sql.NullString `json:"description_fi,omitempty"`
-	OriginEN       sql.NullString `json:"origin_en,omitempty"`
-	OriginFI       sql.NullString `json:"origin_fi,omitempty"`
-	UnitEN         sql.NullString `json:"unit_en,omitempty"`
-	UnitFI         sql.NullString `json:"unit_fi,omitempty"`
-	BadgeEN        sql.NullString `json:"badge_en,omitempty"`
-	BadgeFI        sql.NullString `json:"badge_fi,omitempty"`
-	FeaturesEN     sql.NullString `json:"features_en,omitempty"` // Assuming JSONB is read as a string
-	FeaturesFI     sql.NullString `json:"features_fi,omitempty"` // We can unmarshal this later if needed
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
+	ID          string         `json:"id"`
+	CategoryID  sql.NullString `json:"category_id,omitempty"`
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description,omitempty"`
+	// Options are the axes the variants differ along, e.g. ["size"].
+	Options       []string       `json:"options"`
+	NameEN        sql.NullString `json:"name_en,omitempty"`
+	NameFI        sql.NullString `json:"name_fi,omitempty"`
+	DescriptionEN sql.NullString `json:"description_en,omitempty"`
+	DescriptionFI sql.NullString `json:"description_fi,omitempty"`
+	OriginEN      sql.NullString `json:"origin_en,omitempty"`
+	OriginFI      sql.NullString `json:"origin_fi,omitempty"`
+	UnitEN        sql.NullString `json:"unit_en,omitempty"`
+	UnitFI        sql.NullString `json:"unit_fi,omitempty"`
+	BadgeEN       sql.NullString `json:"badge_en,omitempty"`
+	BadgeFI       sql.NullString `json:"badge_fi,omitempty"`
+	FeaturesEN    sql.NullString `json:"features_en,omitempty"` // Assuming JSONB is read as a string
+	FeaturesFI    sql.NullString `json:"features_fi,omitempty"` // We can unmarshal this later if needed
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	// Variants is only filled in by the repository methods that say so.
+	Variants []*ProductVariant `json:"variants,omitempty"`
+}
+
+// ProductVariant corresponds to the "product_variants" table.
+type ProductVariant struct {
+	ID        string `json:"id"`
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	// Options has a value for each of the product's options, e.g. {"size": "500 g"}.
+	Options        map[string]string `json:"options"`
+	Price          float64           `json:"price"`
+	InventoryCount int               `json:"inventory_count"`
+	Position       int               `json:"position"`
+	Sale           *Sale             `json:"sale,omitempty"` // Nil unless a sale is set
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
 }
 
 // ProductImage corresponds to the "product_images" table.
 type ProductImage struct {
 	ID        string         `json:"id"`
 	ProductID string         `json:"product_id"`
+	VariantID sql.NullString `json:"variant_id,omitempty"` // Set for images of one variant
 	URL       string         `json:"url"`
 	AltText   sql.NullString `json:"alt_text,omitempty"`
 	AltEN     sql.NullString `json:"alt_en,omitempty"`
@@ -0,0 +1,13 @@
+// backend/internal/models/rate_limit.go
+package models
+
+import "time"
+
+// RateLimitBucket corresponds to the "rate_limit_buckets" table. Tokens is
+// the balance after the most recent take; Allowed is whether that take got a token.
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
+}
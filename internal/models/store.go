@@ -7,19 +7,25 @@ import "time"
 // This is the raw data structure.
 type CartItem struct {
 	UserID    string    `json:"user_id"`
-	ProductID string    `json:"product_id"`
+	VariantID string    `json:"variant_id"`
 	Quantity  int       `json:"quantity"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // CartItemDetail is a DTO (Data Transfer Object) used for API responses.
-// It enriches the CartItem with details from the products table.
+// It enriches the CartItem with details from the products and
+// product_variants tables.
 type CartItemDetail struct {
-	ProductID     string    `json:"product_id"`
-	Quantity      int       `json:"quantity"`
-	ProductName   string    `json:"product_name"`   // From products table
-	PricePerUnit  float64   `json:"price_per_unit"` // From products table
-	LineItemTotal float64   `json:"line_item_total"`
-	AddedAt       time.Time `json:"added_at"`
+	VariantID     string            `json:"variant_id"`
+	ProductID     string            `json:"product_id"`
+	SKU           string            `json:"sku"`
+	Options       map[string]string `json:"options"`
+	Quantity      int               `json:"quantity"`
+	ProductName   string            `json:"product_name"`
+	PricePerUnit  float64           `json:"price_per_unit"` // The variant's effective price
+	RegularPrice  float64           `json:"regular_price"`  // The price without a sale
+	OnSale        bool              `json:"on_sale"`
+	LineItemTotal float64           `json:"line_item_total"`
+	AddedAt       time.Time         `json:"added_at"`
 }
@@ -3,16 +3,26 @@ package models
 
 import "time"
 
+// Supported values for User.PreferredLanguage.
+const (
+	LanguageEN = "en"
+	LanguageFI = "fi"
+)
+
 // User corresponds to the "users" table in the database.
 type User struct {
-	ID           string    `json:"id"`
-	FullName     string    `json:"full_name"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`               // The dash ensures this is never sent in JSON responses
-	Phone        *string   `json:"phone,omitempty"` // Pointer for nullable fields
-	RoleID       *string   `json:"role_id,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                string     `json:"id"`
+	FullName          string     `json:"full_name"`
+	Email             string     `json:"email"`
+	PasswordHash      string     `json:"-"`               // The dash ensures this is never sent in JSON responses
+	Phone             *string    `json:"phone,omitempty"` // Pointer for nullable fields
+	RoleID            *string    `json:"role_id,omitempty"`
+	Role              *string    `json:"role,omitempty"` // Role name, joined from the roles table on reads
+	PreferredLanguage string     `json:"preferred_language"`
+	TokenVersion      int        `json:"-"` // Bumped on password change to invalidate previously issued tokens
+	EmailVerifiedAt   *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // Role corresponds to the "roles" table.
@@ -23,3 +33,21 @@ type Role struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// Purposes for UserToken.
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// UserToken corresponds to the "user_tokens" table.
+// Only the SHA-256 hash of the token is stored; the raw value is emailed to the user.
+type UserToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Purpose   string     `json:"purpose"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,133 @@
+// backend/internal/pgtest/cluster.go
+package pgtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// localCluster is a Postgres cluster in a temporary directory, owned by this
+// test process.
+type localCluster struct {
+	pgCtl string
+	dir   string
+	dsn   string
+}
+
+// startLocalCluster runs initdb and pg_ctl to start a cluster that only
+// listens on localhost. fsync is off: the data is thrown away anyway.
+func startLocalCluster() (*localCluster, error) {
+	binDir, err := findPostgresBin()
+	if err != nil {
+		return nil, err
+	}
+	if os.Geteuid() == 0 {
+		return nil, errors.New("initdb refuses to run as root")
+	}
+
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return nil, err
+	}
+	c := &localCluster{pgCtl: filepath.Join(binDir, "pg_ctl"), dir: dir}
+	data := filepath.Join(dir, "data")
+
+	if err := run(filepath.Join(binDir, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync"); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	// The socket goes in dir too, since the default location may not be writable.
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c synchronous_commit=off -c full_page_writes=off", port, dir)
+	if err := run(c.pgCtl, "start", "-w", "-D", data, "-l", filepath.Join(dir, "server.log"), "-o", opts); err != nil {
+		log, _ := os.ReadFile(filepath.Join(dir, "server.log"))
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%w\n%s", err, log)
+	}
+
+	c.dsn = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+	return c, nil
+}
+
+func (c *localCluster) stop() error {
+	defer os.RemoveAll(c.dir)
+	return run(c.pgCtl, "stop", "-w", "-m", "immediate", "-D", filepath.Join(c.dir, "data"))
+}
+
+// findPostgresBin returns the directory holding initdb and pg_ctl: $PGTEST_BIN,
+// then $PATH, then the places common packages install them.
+func findPostgresBin() (string, error) {
+	if dir := os.Getenv("PGTEST_BIN"); dir != "" {
+		return dir, nil
+	}
+	if path, err := exec.LookPath("pg_ctl"); err == nil {
+		return filepath.Dir(path), nil
+	}
+
+	var candidates []string
+	for _, pattern := range []string{
+		"/usr/lib/postgresql/*/bin",         // Debian, Ubuntu
+		"/usr/pgsql-*/bin",                  // RHEL, Fedora
+		"/opt/homebrew/opt/postgresql*/bin", // Homebrew on Apple silicon
+		"/usr/local/opt/postgresql*/bin",    // Homebrew on Intel
+	} {
+		matches, _ := filepath.Glob(pattern)
+		candidates = append(candidates, matches...)
+	}
+	// Prefer the newest version; the globs sort lexically, so compare numerically.
+	sort.SliceStable(candidates, func(i, j int) bool { return version(candidates[i]) > version(candidates[j]) })
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, "pg_ctl")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", errors.New("pg_ctl not found; set PGTEST_BIN or TEST_DATABASE_URL")
+}
+
+// version extracts the major version from an install path such as
+// /usr/lib/postgresql/16/bin, or returns 0.
+func version(dir string) int {
+	var digits []byte
+	for _, r := range []byte(filepath.Dir(dir)) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, r)
+		case len(digits) > 0:
+			n, _ := strconv.Atoi(string(digits))
+			return n
+		}
+	}
+	n, _ := strconv.Atoi(string(digits))
+	return n
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func run(name string, args ...string) error {
+	var out bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w\n%s", filepath.Base(name), err, out.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// backend/internal/pgtest/fixtures.go
+package pgtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The fixtures insert rows with plain SQL rather than through the
+// repositories, so they don't depend on the code under test.
+
+// SeedUser inserts a verified user with the given password and returns its ID.
+// role is a row in the roles table, "customer" or "admin", or "" for none.
+func SeedUser(tb testing.TB, pool *pgxpool.Pool, email, password, role string) string {
+	tb.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var id string
+	err = pool.QueryRow(context.Background(), `
+		INSERT INTO users (full_name, email, password_hash, role_id, email_verified_at)
+		VALUES ($1, $2, $3, (SELECT id FROM roles WHERE name = NULLIF($4, '')), NOW())
+		RETURNING id
+	`, "Seeded User", email, string(hash), role).Scan(&id)
+	if err != nil {
+		tb.Fatalf("seeding user %s: %v", email, err)
+	}
+	return id
+}
+
+// SeedCategory inserts a category and returns its ID.
+func SeedCategory(tb testing.TB, pool *pgxpool.Pool, name string) string {
+	tb.Helper()
+	var id string
+	err := pool.QueryRow(context.Background(), `INSERT INTO categories (name) VALUES ($1) RETURNING id`, name).Scan(&id)
+	if err != nil {
+		tb.Fatalf("seeding category %s: %v", name, err)
+	}
+	return id
+}
+
+// SeedProduct inserts a product with a single variant, whose SKU is the
+// product's ID, and returns the product's ID. categoryID may be "".
+func SeedProduct(tb testing.TB, pool *pgxpool.Pool, categoryID, name string, price float64, inventory int) string {
+	tb.Helper()
+	var id string
+	err := pool.QueryRow(context.Background(), `
+		WITH p AS (
+			INSERT INTO products (category_id, name)
+			VALUES (NULLIF($1, '')::uuid, $2)
+			RETURNING id
+		)
+		INSERT INTO product_variants (product_id, sku, price, inventory_count)
+		SELECT id, id::text, $3, $4 FROM p
+		RETURNING product_id
+	`, categoryID, name, price, inventory).Scan(&id)
+	if err != nil {
+		tb.Fatalf("seeding product %s: %v", name, err)
+	}
+	return id
+}
@@ -0,0 +1,127 @@
+// backend/internal/pgtest/pgtest.go
+
+// Package pgtest gives each test an empty Postgres schema with the
+// migrations applied, so repository and end-to-end tests can run in parallel
+// without seeing each other's rows.
+//
+// The server comes from TEST_DATABASE_URL or, when that is unset and the
+// package's tests use Main, from a throwaway cluster started with the local
+// initdb and pg_ctl binaries. Tests are skipped when neither is available.
+package pgtest
+
+import (
+	"backend/migrations"
+	"backend/pkg/database"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	mainCalled bool
+
+	serverOnce sync.Once
+	server     *pgxpool.Pool // Connects to the default schema; used to create and drop test schemas
+	serverErr  error         // Why there is no server, reported when a test asks for one
+	cluster    *localCluster // Set when this process launched the server
+)
+
+// Main runs the tests and then shuts down the cluster New may have launched.
+// Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { pgtest.Main(m) }
+//
+// Without it, New only uses TEST_DATABASE_URL, since nothing would stop a
+// launched cluster.
+func Main(m *testing.M) {
+	mainCalled = true
+	code := m.Run()
+	if server != nil {
+		server.Close()
+	}
+	if cluster != nil {
+		if err := cluster.stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "pgtest: %v\n", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// connect finds or launches a server. A TEST_DATABASE_URL that doesn't work
+// is an error; not finding Postgres binaries is a reason to skip.
+func connect() {
+	ctx := context.Background()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		if !mainCalled {
+			serverErr = errSkip("TEST_DATABASE_URL is not set")
+			return
+		}
+		c, err := startLocalCluster()
+		if err != nil {
+			serverErr = errSkip(fmt.Sprintf("TEST_DATABASE_URL is not set and no local Postgres could be started: %v", err))
+			return
+		}
+		cluster, dsn = c, c.dsn
+	}
+
+	server, serverErr = database.Connect(ctx, dsn, database.PoolOptions{MaxConns: 4})
+}
+
+// errSkip marks a missing server as a skip rather than a failure.
+type errSkip string
+
+func (e errSkip) Error() string { return string(e) }
+
+// New returns a pool whose connections see only a fresh schema with every
+// migration applied. The schema is dropped when the test ends.
+func New(tb testing.TB) *pgxpool.Pool {
+	tb.Helper()
+	serverOnce.Do(connect)
+	if skip, ok := serverErr.(errSkip); ok {
+		tb.Skip(string(skip))
+	}
+	if serverErr != nil {
+		tb.Fatalf("connecting to the test database: %v", serverErr)
+	}
+	ctx := context.Background()
+
+	var b [6]byte
+	rand.Read(b[:])
+	schema := "pgtest_" + hex.EncodeToString(b[:])
+	if _, err := server.Exec(ctx, "CREATE SCHEMA "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		tb.Fatalf("creating schema: %v", err)
+	}
+	tb.Cleanup(func() {
+		if _, err := server.Exec(ctx, "DROP SCHEMA "+pgx.Identifier{schema}.Sanitize()+" CASCADE"); err != nil {
+			tb.Errorf("dropping schema %s: %v", schema, err)
+		}
+	})
+
+	cfg := server.Config()
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	cfg.MinConns = 0
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		tb.Fatalf("connecting to schema %s: %v", schema, err)
+	}
+	// Registered after the DROP, so it runs first: the schema can't be
+	// dropped while these connections hold locks in it.
+	tb.Cleanup(pool.Close)
+
+	migrator, err := database.NewMigrator(database.OpenDB(pool), migrations.FS)
+	if err != nil {
+		tb.Fatalf("loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		tb.Fatalf("migrating schema %s: %v", schema, err)
+	}
+	return pool
+}
@@ -4,61 +4,334 @@ package repository
 import (
 	"backend/internal/models"
 	"context"
-	"database/sql"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrRoleNotFound is returned when no role has the given name.
+var ErrRoleNotFound = errors.New("role not found")
+
 // AdminRepository abstracts privileged write operations.
 type AdminRepository interface {
+	// FindProductForUpdate reads a product with its variants and, inside a
+	// transaction, locks them until the transaction ends, so callers can
+	// record what they change.
+	FindProductForUpdate(ctx context.Context, id string) (*models.Product, error)
+	// CreateProduct inserts the product itself; see CreateVariants.
 	CreateProduct(ctx context.Context, product *models.Product) error
+	// CreateProducts bulk-inserts products and returns how many were inserted.
+	// IDs are generated up front so that variants can refer to them, but the
+	// timestamps are not filled in.
+	CreateProducts(ctx context.Context, products []*models.Product) (int64, error)
+	// UpdateProduct saves a product's category, name, description and badges.
 	UpdateProduct(ctx context.Context, product *models.Product) error
 	DeleteProduct(ctx context.Context, id string) error
+	// AdjustProductInventory changes the stock of a product's only variant. It
+	// returns ErrMultipleVariants if the product has several.
 	AdjustProductInventory(ctx context.Context, id string, change int) (int, error)
+
+	// CreateVariants inserts variants in one statement, filling in their IDs
+	// and timestamps.
+	CreateVariants(ctx context.Context, variants []*models.ProductVariant) error
+	// FindVariantForUpdate is FindProductForUpdate for a single variant.
+	FindVariantForUpdate(ctx context.Context, id string) (*models.ProductVariant, error)
+	// UpdateVariant saves a variant's SKU, options and position and fills in
+	// the rest of variant from the stored row. Prices change through
+	// PriceRepository, which keeps their history.
+	UpdateVariant(ctx context.Context, variant *models.ProductVariant) error
+	DeleteVariant(ctx context.Context, id string) error
+	AdjustVariantInventory(ctx context.Context, id string, change int) (int, error)
+
+	// SetUserRole gives a user the named role and returns the role they had,
+	// nil for none. Changing the role bumps the user's token version, which
+	// revokes every token issued before.
+	SetUserRole(ctx context.Context, userID, role string) (*string, error)
 }
 
 type postgresAdminRepository struct {
-	db *sql.DB
+	db *instrumentedDB
 }
 
-func NewPostgresAdminRepository(db *sql.DB) AdminRepository {
-	return &postgresAdminRepository{db: db}
+func NewPostgresAdminRepository(pool *pgxpool.Pool) AdminRepository {
+	return &postgresAdminRepository{db: instrument(pool, "admin")}
+}
+
+func (r *postgresAdminRepository) FindProductForUpdate(ctx context.Context, id string) (*models.Product, error) {
+	p := new(models.Product)
+	query := `
+		SELECT id, category_id, name, description, options, badge_en, badge_fi, created_at, updated_at
+		FROM products
+		WHERE id = $1
+		FOR UPDATE
+	`
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.CategoryID, &p.Name, &p.Description, &p.Options, &p.BadgeEN, &p.BadgeFI, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT ` + variantColumns + `
+		FROM product_variants
+		WHERE product_id = $1
+		ORDER BY position, created_at
+		FOR UPDATE
+	`
+	p.Variants, err = queryVariants(ctx, r.db, query, id)
+	return p, err
 }
 
 func (r *postgresAdminRepository) CreateProduct(ctx context.Context, p *models.Product) error {
 	query := `
-		INSERT INTO products (category_id, name, description, price, inventory_count)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO products (category_id, name, description, options, badge_en, badge_fi)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, updated_at
 	`
 	// Note: In a real app, you'd insert all the i18n fields too.
-	return r.db.QueryRowContext(ctx, query, p.CategoryID, p.Name, p.Description, p.Price, p.InventoryCount).Scan(
+	return r.db.QueryRow(ctx, query, p.CategoryID, p.Name, p.Description, optionNames(p.Options), p.BadgeEN, p.BadgeFI).Scan(
 		&p.ID, &p.CreatedAt, &p.UpdatedAt,
 	)
 }
 
+// CreateProducts uses COPY, which is far faster than one INSERT per row for
+// large imports.
+func (r *postgresAdminRepository) CreateProducts(ctx context.Context, products []*models.Product) (int64, error) {
+	for _, p := range products {
+		if p.ID == "" {
+			p.ID = newUUID()
+		}
+	}
+	columns := []string{"id", "category_id", "name", "description", "options", "badge_en", "badge_fi"}
+	return r.db.CopyFrom(ctx, pgx.Identifier{"products"}, columns, pgx.CopyFromSlice(len(products), func(i int) ([]any, error) {
+		p := products[i]
+		return []any{p.ID, p.CategoryID, p.Name, p.Description, optionNames(p.Options), p.BadgeEN, p.BadgeFI}, nil
+	}))
+}
+
+// optionNames stores a product without options as an empty array, not NULL.
+func optionNames(options []string) []string {
+	if options == nil {
+		return []string{}
+	}
+	return options
+}
+
 func (r *postgresAdminRepository) UpdateProduct(ctx context.Context, p *models.Product) error {
 	query := `
 		UPDATE products
-		SET category_id = $2, name = $3, description = $4, price = $5, inventory_count = $6, updated_at = NOW()
+		SET category_id = $2, name = $3, description = $4, badge_en = $5, badge_fi = $6, updated_at = NOW()
 		WHERE id = $1
+		RETURNING created_at, updated_at
 	`
-	_, err := r.db.ExecContext(ctx, query, p.ID, p.CategoryID, p.Name, p.Description, p.Price, p.InventoryCount)
+	err := r.db.QueryRow(ctx, query, p.ID, p.CategoryID, p.Name, p.Description, p.BadgeEN, p.BadgeFI).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrProductNotFound
+	}
 	return err
 }
 
 func (r *postgresAdminRepository) DeleteProduct(ctx context.Context, id string) error {
 	query := `DELETE FROM products WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
+	tag, err := r.db.Exec(ctx, query, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrProductNotFound
+	}
 	return err
 }
 
 func (r *postgresAdminRepository) AdjustProductInventory(ctx context.Context, id string, change int) (int, error) {
 	query := `
-		UPDATE products
+		WITH candidates AS (
+			SELECT id FROM product_variants WHERE product_id = $2 FOR UPDATE
+		)
+		UPDATE product_variants
+		SET inventory_count = inventory_count + $1, updated_at = NOW()
+		WHERE product_id = $2 AND (SELECT COUNT(*) FROM candidates) = 1
+		RETURNING inventory_count
+	`
+	var newInventory int
+	err := r.db.QueryRow(ctx, query, change, id).Scan(&newInventory)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return newInventory, err
+	}
+
+	var variants int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM product_variants WHERE product_id = $1`, id).Scan(&variants); err != nil {
+		return 0, err
+	}
+	if variants == 0 {
+		return 0, ErrProductNotFound
+	}
+	return 0, ErrMultipleVariants
+}
+
+func (r *postgresAdminRepository) CreateVariants(ctx context.Context, variants []*models.ProductVariant) error {
+	n := len(variants)
+	ids, productIDs, skus, options := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	prices, inventory, positions := make([]float64, n), make([]int, n), make([]int, n)
+	byID := make(map[string]*models.ProductVariant, n)
+	for i, v := range variants {
+		opts, err := json.Marshal(variantOptions(v.Options))
+		if err != nil {
+			return err
+		}
+		ids[i], productIDs[i], skus[i], options[i] = newUUID(), v.ProductID, v.SKU, string(opts)
+		prices[i], inventory[i], positions[i] = v.Price, v.InventoryCount, v.Position
+		byID[ids[i]] = v
+	}
+
+	// RETURNING doesn't promise to keep the input order, so rows are matched
+	// up by the IDs generated here.
+	query := `
+		INSERT INTO product_variants (id, product_id, sku, options, price, inventory_count, position)
+		SELECT id, product_id, sku, options::jsonb, price, inventory_count, position
+		FROM unnest($1::uuid[], $2::uuid[], $3::text[], $4::text[], $5::float8[], $6::int[], $7::int[])
+			AS v (id, product_id, sku, options, price, inventory_count, position)
+		RETURNING id, created_at, updated_at
+	`
+	rows, err := r.db.Query(ctx, query, ids, productIDs, skus, options, prices, inventory, positions)
+	if err != nil {
+		return variantWriteError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&id, &createdAt, &updatedAt); err != nil {
+			return err
+		}
+		v := byID[id]
+		v.ID, v.CreatedAt, v.UpdatedAt = id, createdAt, updatedAt
+	}
+	return variantWriteError(rows.Err())
+}
+
+// variantOptions stores a variant without options as an empty object, not null.
+func variantOptions(options map[string]string) map[string]string {
+	if options == nil {
+		return map[string]string{}
+	}
+	return options
+}
+
+// variantWriteError reports the unique constraints on product_variants as
+// ErrDuplicateSKU and ErrDuplicateVariant.
+func variantWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		switch pgErr.ConstraintName {
+		case "product_variants_sku_key":
+			return ErrDuplicateSKU
+		case "product_variants_options_key":
+			return ErrDuplicateVariant
+		}
+	}
+	return err
+}
+
+func (r *postgresAdminRepository) FindVariantForUpdate(ctx context.Context, id string) (*models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE id = $1 FOR UPDATE`
+	v, err := scanVariant(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVariantNotFound
+	}
+	return v, err
+}
+
+func (r *postgresAdminRepository) UpdateVariant(ctx context.Context, v *models.ProductVariant) error {
+	query := `
+		UPDATE product_variants
+		SET sku = $2, options = $3, position = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + variantColumns + `
+	`
+	updated, err := scanVariant(r.db.QueryRow(ctx, query, v.ID, v.SKU, variantOptions(v.Options), v.Position))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVariantNotFound
+	}
+	if err != nil {
+		return variantWriteError(err)
+	}
+	*v = *updated
+	return nil
+}
+
+func (r *postgresAdminRepository) DeleteVariant(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_variants WHERE id = $1`, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrVariantNotFound
+	}
+	return err
+}
+
+func (r *postgresAdminRepository) AdjustVariantInventory(ctx context.Context, id string, change int) (int, error) {
+	query := `
+		UPDATE product_variants
 		SET inventory_count = inventory_count + $1, updated_at = NOW()
 		WHERE id = $2
 		RETURNING inventory_count
 	`
 	var newInventory int
-	err := r.db.QueryRowContext(ctx, query, change, id).Scan(&newInventory)
+	err := r.db.QueryRow(ctx, query, change, id).Scan(&newInventory)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrVariantNotFound
+	}
 	return newInventory, err
 }
+
+// newUUID returns a random version 4 UUID, for rows whose ID must be known
+// before they are inserted.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (r *postgresAdminRepository) SetUserRole(ctx context.Context, userID, role string) (*string, error) {
+	// The CTE reads the old role before the update; RETURNING would see the new one.
+	query := `
+		WITH target AS (
+			SELECT u.id, old_r.name AS old_role, new_r.id AS new_role_id
+			FROM users u
+			LEFT JOIN roles old_r ON old_r.id = u.role_id
+			LEFT JOIN roles new_r ON new_r.name = $2
+			WHERE u.id = $1
+			FOR UPDATE OF u
+		)
+		UPDATE users u
+		SET role_id = t.new_role_id,
+			token_version = token_version + CASE WHEN u.role_id IS DISTINCT FROM t.new_role_id THEN 1 ELSE 0 END,
+			updated_at = NOW()
+		FROM target t
+		WHERE u.id = t.id AND t.new_role_id IS NOT NULL
+		RETURNING t.old_role
+	`
+	var previous *string
+	err := r.db.QueryRow(ctx, query, userID, role).Scan(&previous)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return previous, err
+	}
+
+	// Nothing was updated: tell an unknown user from an unknown role.
+	var userExists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&userExists); err != nil {
+		return nil, err
+	}
+	if !userExists {
+		return nil, ErrUserNotFound
+	}
+	return nil, ErrRoleNotFound
+}
@@ -0,0 +1,29 @@
+// backend/internal/repository/contract_test.go
+package repository_test
+
+import (
+	"backend/internal/pgtest"
+	"backend/internal/repository"
+	"backend/internal/repository/repotest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+// TestPostgresContract runs the repository contract against Postgres, each
+// subtest in a fresh schema.
+func TestPostgresContract(t *testing.T) {
+	repotest.RunContract(t, func(t *testing.T) repotest.Repositories {
+		pool := pgtest.New(t)
+		return repotest.Repositories{
+			Users:    repository.NewPostgresUserRepository(pool),
+			Products: repository.NewPostgresProductRepository(pool),
+			Store:    repository.NewPostgresStoreRepository(pool),
+			Admin:    repository.NewPostgresAdminRepository(pool),
+			Prices:   repository.NewPostgresPriceRepository(pool),
+			AddCategory: func(t *testing.T, name string) string {
+				return pgtest.SeedCategory(t, pool, name)
+			},
+		}
+	})
+}
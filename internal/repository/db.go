@@ -0,0 +1,138 @@
+// backend/internal/repository/db.go
+package repository
+
+import (
+	"backend/pkg/metrics"
+	"backend/pkg/tracing"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var queryDuration = metrics.NewHistogramVec(
+	"db_query_duration_seconds",
+	"Time spent executing SQL queries, by repository and statement type.",
+	nil,
+	"repository", "operation",
+)
+
+func init() {
+	metrics.MustRegister(queryDuration)
+}
+
+var tracer = otel.Tracer("backend/internal/repository")
+
+// instrumentedDB is what the Postgres repositories query through. It wraps the
+// shared pool, records how long each query takes under the repository's name
+// and creates a client span carrying the sanitized SQL. Queries join the
+// transaction in the context, if TxManager started one on the same pool.
+//
+// For Query the span and timing cover executing the query, not iterating over
+// the rows; for QueryRow they end when the row is scanned.
+type instrumentedDB struct {
+	pool       *pgxpool.Pool
+	repository string
+}
+
+func instrument(pool *pgxpool.Pool, repository string) *instrumentedDB {
+	return &instrumentedDB{pool: pool, repository: repository}
+}
+
+// querier is what *pgxpool.Pool and pgx.Tx have in common.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error)
+}
+
+// conn returns the transaction in ctx, or the pool when there is none.
+func (d *instrumentedDB) conn(ctx context.Context) querier {
+	if state, ok := txFrom(ctx, d.pool); ok {
+		return state.tx
+	}
+	return d.pool
+}
+
+func (d *instrumentedDB) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	ctx, done := d.start(ctx, query)
+	tag, err := d.conn(ctx).Exec(ctx, query, args...)
+	done(err)
+	return tag, err
+}
+
+func (d *instrumentedDB) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	ctx, done := d.start(ctx, query)
+	rows, err := d.conn(ctx).Query(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (d *instrumentedDB) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	ctx, done := d.start(ctx, query)
+	return instrumentedRow{row: d.conn(ctx).QueryRow(ctx, query, args...), done: done}
+}
+
+// CopyFrom bulk-loads rows with COPY. The span is named after the table.
+func (d *instrumentedDB) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	ctx, done := d.start(ctx, "copy "+table.Sanitize()+" ("+strings.Join(columns, ", ")+") from stdin")
+	n, err := d.conn(ctx).CopyFrom(ctx, table, columns, src)
+	done(err)
+	return n, err
+}
+
+// instrumentedRow ends the query's span once the row has been scanned, which
+// is when pgx reports the query's error.
+type instrumentedRow struct {
+	row  pgx.Row
+	done func(err error)
+}
+
+func (r instrumentedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.done(err)
+	return err
+}
+
+// start opens the span for one query; done ends it and records the duration.
+func (d *instrumentedDB) start(ctx context.Context, query string) (context.Context, func(err error)) {
+	operation := statementType(query)
+	begin := time.Now()
+	ctx, span := tracer.Start(ctx, d.repository+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", tracing.SanitizeSQL(query)),
+			attribute.String("app.repository", d.repository),
+		),
+	)
+	return ctx, func(err error) {
+		queryDuration.WithLabelValues(d.repository, operation).Observe(time.Since(begin).Seconds())
+		// pgx.ErrNoRows is how lookups report "not found"; it isn't a failed query.
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// statementType returns the leading SQL keyword ("select", "insert", ...),
+// which keeps the operation label low-cardinality.
+func statementType(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
+}
@@ -0,0 +1,110 @@
+// backend/internal/repository/log_repository.go
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LogRepository is the append-only audit trail. Entries are never updated or
+// deleted; the audit_log table rejects both.
+type LogRepository interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+	// Find returns the entries matching filter, newest first.
+	Find(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
+}
+
+// AuditFilter selects audit entries. Empty fields match everything; From is
+// inclusive and To exclusive.
+type AuditFilter struct {
+	ActorID    string
+	EntityType string
+	EntityID   string
+	From, To   time.Time
+	Limit      int
+	Offset     int
+}
+
+type postgresLogRepository struct {
+	db *instrumentedDB
+}
+
+func NewPostgresLogRepository(pool *pgxpool.Pool) LogRepository {
+	return &postgresLogRepository{db: instrument(pool, "log")}
+}
+
+func (r *postgresLogRepository) Append(ctx context.Context, e *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, ip_address, request_id, details, changes)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query, e.ActorID, e.Action, e.EntityType, e.EntityID, e.IPAddress, e.RequestID, jsonbArg(e.Details), jsonbArg(e.Changes)).Scan(
+		&e.ID, &e.CreatedAt,
+	)
+}
+
+// jsonbArg passes raw JSON to the driver, turning an empty document into NULL.
+func jsonbArg(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
+}
+
+func (r *postgresLogRepository) Find(ctx context.Context, f AuditFilter) ([]*models.AuditEntry, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if f.ActorID != "" {
+		add("actor_id = ?", f.ActorID)
+	}
+	if f.EntityType != "" {
+		add("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != "" {
+		add("entity_id = ?", f.EntityID)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", f.To)
+	}
+
+	query := `
+		SELECT id, actor_id, action, entity_type, entity_id, COALESCE(ip_address, ''), COALESCE(request_id, ''), details, changes, created_at
+		FROM audit_log
+	`
+	if len(where) > 0 {
+		query += "WHERE " + strings.Join(where, " AND ") + "\n"
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += "ORDER BY created_at DESC, id DESC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		e := new(models.AuditEntry)
+		var details, changes []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID, &e.IPAddress, &e.RequestID, &details, &changes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Details, e.Changes = details, changes
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,152 @@
+// backend/internal/repository/login_attempt_repository.go
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoginAttemptRepository tracks failed logins per key (account or client IP).
+// The in-memory implementation suits a single instance; use Postgres when
+// several API instances must share lockout state.
+type LoginAttemptRepository interface {
+	// Get returns the throttle state for key, or a zero state if there is none.
+	Get(ctx context.Context, key string) (*models.LoginThrottle, error)
+	// RecordFailure adds a failure, restarting the count if the previous failure
+	// is older than window, and returns the updated state.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginThrottle, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// --- Postgres ---
+
+type postgresLoginAttemptRepository struct {
+	db *instrumentedDB
+}
+
+func NewPostgresLoginAttemptRepository(pool *pgxpool.Pool) LoginAttemptRepository {
+	return &postgresLoginAttemptRepository{db: instrument(pool, "login_attempt")}
+}
+
+func (r *postgresLoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	t := &models.LoginThrottle{Key: key}
+	query := `SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1`
+	err := r.db.QueryRow(ctx, query, key).Scan(&t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, nil
+	}
+	return t, err
+}
+
+func (r *postgresLoginAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginThrottle, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures, last_failure_at, locked_until
+	`
+	t := &models.LoginThrottle{Key: key}
+	err := r.db.QueryRow(ctx, query, key, window.Seconds()).Scan(&t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	return t, err
+}
+
+func (r *postgresLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at, locked_until)
+		VALUES ($1, 0, NOW(), $2)
+		ON CONFLICT (key) DO UPDATE SET locked_until = EXCLUDED.locked_until
+	`
+	_, err := r.db.Exec(ctx, query, key, until)
+	return err
+}
+
+func (r *postgresLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1`
+	_, err := r.db.Exec(ctx, query, key)
+	return err
+}
+
+// --- In-memory ---
+
+// memorySweepThreshold is the map size above which stale entries are pruned.
+const memorySweepThreshold = 10000
+
+type memoryLoginAttemptRepository struct {
+	mu      sync.Mutex
+	entries map[string]models.LoginThrottle
+}
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{entries: make(map[string]models.LoginThrottle)}
+}
+
+func (r *memoryLoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.entries[key]
+	if !ok {
+		return &models.LoginThrottle{Key: key}, nil
+	}
+	return &t, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if len(r.entries) > memorySweepThreshold {
+		r.sweep(now, window)
+	}
+
+	t := r.entries[key]
+	t.Key = key
+	if now.Sub(t.LastFailureAt) > window {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailureAt = now
+	r.entries[key] = t
+	return &t, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.entries[key]
+	t.Key = key
+	t.LockedUntil = &until
+	r.entries[key] = t
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.entries, key)
+	return nil
+}
+
+// sweep drops entries that are outside the window and not locked. Callers hold mu.
+func (r *memoryLoginAttemptRepository) sweep(now time.Time, window time.Duration) {
+	for key, t := range r.entries {
+		if now.Sub(t.LastFailureAt) > window && (t.LockedUntil == nil || t.LockedUntil.Before(now)) {
+			delete(r.entries, key)
+		}
+	}
+}
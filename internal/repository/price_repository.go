@@ -0,0 +1,237 @@
+// backend/internal/repository/price_repository.go
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrPriceChangeNotFound is returned when no pending scheduled price change
+// has the given ID.
+var ErrPriceChangeNotFound = errors.New("scheduled price change not found")
+
+// PriceRepository stores what variants cost: their regular price, sales,
+// scheduled changes of the regular price and the history of past changes.
+type PriceRepository interface {
+	// SetPrice changes a variant's regular price and returns the previous one.
+	// It doesn't record the change; see AppendHistory.
+	SetPrice(ctx context.Context, variantID string, price float64) (float64, error)
+	// SetSale puts a variant on sale, replacing any sale it had. A nil sale
+	// ends it.
+	SetSale(ctx context.Context, variantID string, sale *models.Sale) error
+	// SalesChangedBetween reports whether any sale started or ended after
+	// from and no later than to.
+	SalesChangedBetween(ctx context.Context, from, to time.Time) (bool, error)
+
+	// SchedulePriceChange stores a pending change, filling in its ID and
+	// creation time.
+	SchedulePriceChange(ctx context.Context, change *models.ScheduledPriceChange) error
+	// FindPendingChanges returns a variant's pending changes, soonest first.
+	FindPendingChanges(ctx context.Context, variantID string) ([]*models.ScheduledPriceChange, error)
+	// CancelPriceChange deletes a pending change and returns it. Applied
+	// changes are history and can't be cancelled.
+	CancelPriceChange(ctx context.Context, id string) (*models.ScheduledPriceChange, error)
+	// FindDueChanges returns up to limit pending changes due by now, oldest
+	// first. Inside a transaction they stay locked until it ends, and rows
+	// another transaction has locked are skipped, so concurrent schedulers
+	// never apply the same change.
+	FindDueChanges(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledPriceChange, error)
+	// MarkApplied records that a pending change has been applied.
+	MarkApplied(ctx context.Context, id string) error
+
+	// AppendHistory records a change of a variant's regular price, filling in
+	// its ID and time. Like the audit log, it doesn't check that the variant
+	// exists.
+	AppendHistory(ctx context.Context, change *models.PriceChange) error
+	// FindHistory returns a variant's price changes, newest first. The history
+	// is kept when the variant is deleted.
+	FindHistory(ctx context.Context, variantID string, limit, offset int) ([]*models.PriceChange, error)
+}
+
+type postgresPriceRepository struct {
+	db *instrumentedDB
+}
+
+func NewPostgresPriceRepository(pool *pgxpool.Pool) PriceRepository {
+	return &postgresPriceRepository{db: instrument(pool, "price")}
+}
+
+func (r *postgresPriceRepository) SetPrice(ctx context.Context, variantID string, price float64) (float64, error) {
+	// The CTE reads the old price before the update; RETURNING would see the new one.
+	query := `
+		WITH old AS (
+			SELECT id, price FROM product_variants WHERE id = $1 FOR UPDATE
+		)
+		UPDATE product_variants v
+		SET price = $2, updated_at = NOW()
+		FROM old
+		WHERE v.id = old.id
+		RETURNING old.price
+	`
+	var previous float64
+	err := r.db.QueryRow(ctx, query, variantID, price).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrVariantNotFound
+	}
+	return previous, err
+}
+
+func (r *postgresPriceRepository) SetSale(ctx context.Context, variantID string, sale *models.Sale) error {
+	var price *float64
+	var startsAt, endsAt *time.Time
+	if sale != nil {
+		price, startsAt, endsAt = &sale.Price, &sale.StartsAt, sale.EndsAt
+	}
+	query := `
+		UPDATE product_variants
+		SET sale_price = $2, sale_starts_at = $3, sale_ends_at = $4, updated_at = NOW()
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, variantID, price, startsAt, endsAt)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrVariantNotFound
+	}
+	return err
+}
+
+func (r *postgresPriceRepository) SalesChangedBetween(ctx context.Context, from, to time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM product_variants
+			WHERE (sale_starts_at > $1 AND sale_starts_at <= $2)
+				OR (sale_ends_at > $1 AND sale_ends_at <= $2)
+		)
+	`
+	var changed bool
+	err := r.db.QueryRow(ctx, query, from, to).Scan(&changed)
+	return changed, err
+}
+
+// scheduledChangeColumns are the columns scanScheduledChange reads, in order.
+const scheduledChangeColumns = `id, variant_id, price, effective_at, created_by, created_at, applied_at`
+
+func scanScheduledChange(row pgx.Row) (*models.ScheduledPriceChange, error) {
+	c := new(models.ScheduledPriceChange)
+	err := row.Scan(&c.ID, &c.VariantID, &c.Price, &c.EffectiveAt, &c.CreatedBy, &c.CreatedAt, &c.AppliedAt)
+	return c, err
+}
+
+func (r *postgresPriceRepository) queryScheduledChanges(ctx context.Context, query string, args ...any) ([]*models.ScheduledPriceChange, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.ScheduledPriceChange
+	for rows.Next() {
+		c, err := scanScheduledChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (r *postgresPriceRepository) SchedulePriceChange(ctx context.Context, c *models.ScheduledPriceChange) error {
+	query := `
+		INSERT INTO scheduled_price_changes (variant_id, price, effective_at, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(ctx, query, c.VariantID, c.Price, c.EffectiveAt, c.CreatedBy).Scan(&c.ID, &c.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrVariantNotFound
+	}
+	return err
+}
+
+func (r *postgresPriceRepository) FindPendingChanges(ctx context.Context, variantID string) ([]*models.ScheduledPriceChange, error) {
+	query := `
+		SELECT ` + scheduledChangeColumns + `
+		FROM scheduled_price_changes
+		WHERE variant_id = $1 AND applied_at IS NULL
+		ORDER BY effective_at, created_at
+	`
+	return r.queryScheduledChanges(ctx, query, variantID)
+}
+
+func (r *postgresPriceRepository) CancelPriceChange(ctx context.Context, id string) (*models.ScheduledPriceChange, error) {
+	query := `
+		DELETE FROM scheduled_price_changes
+		WHERE id = $1 AND applied_at IS NULL
+		RETURNING ` + scheduledChangeColumns
+	c, err := scanScheduledChange(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPriceChangeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *postgresPriceRepository) FindDueChanges(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledPriceChange, error) {
+	query := `
+		SELECT ` + scheduledChangeColumns + `
+		FROM scheduled_price_changes
+		WHERE applied_at IS NULL AND effective_at <= $1
+		ORDER BY effective_at, created_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	return r.queryScheduledChanges(ctx, query, now, limit)
+}
+
+func (r *postgresPriceRepository) MarkApplied(ctx context.Context, id string) error {
+	query := `UPDATE scheduled_price_changes SET applied_at = NOW() WHERE id = $1 AND applied_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrPriceChangeNotFound
+	}
+	return err
+}
+
+func (r *postgresPriceRepository) AppendHistory(ctx context.Context, c *models.PriceChange) error {
+	query := `
+		INSERT INTO price_history (variant_id, old_price, new_price, source, changed_by, scheduled_change_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, changed_at
+	`
+	return r.db.QueryRow(ctx, query, c.VariantID, c.OldPrice, c.NewPrice, c.Source, c.ChangedBy, c.ScheduledChangeID).Scan(
+		&c.ID, &c.ChangedAt,
+	)
+}
+
+func (r *postgresPriceRepository) FindHistory(ctx context.Context, variantID string, limit, offset int) ([]*models.PriceChange, error) {
+	query := `
+		SELECT id, variant_id, old_price, new_price, source, changed_by, scheduled_change_id, changed_at
+		FROM price_history
+		WHERE variant_id = $1
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, variantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.PriceChange
+	for rows.Next() {
+		c := new(models.PriceChange)
+		if err := rows.Scan(&c.ID, &c.VariantID, &c.OldPrice, &c.NewPrice, &c.Source, &c.ChangedBy, &c.ScheduledChangeID, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
@@ -4,34 +4,61 @@ package repository
 import (
 	"backend/internal/models"
 	"context"
-	"database/sql"
 	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrProductNotFound is returned when no product has the given ID.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrVariantNotFound is returned when no variant has the given ID.
+	ErrVariantNotFound = errors.New("variant not found")
+	// ErrMultipleVariants is returned by product-level operations that need a
+	// product to have exactly one variant.
+	ErrMultipleVariants = errors.New("product has several variants")
+	// ErrDuplicateSKU is returned when another variant already has the SKU.
+	ErrDuplicateSKU = errors.New("SKU already in use")
+	// ErrDuplicateVariant is returned when another variant of the same product
+	// has the same option values.
+	ErrDuplicateVariant = errors.New("a variant with these options already exists")
 )
 
 // ProductRepository abstracts database operations for products and categories.
+// Products are returned without their variants; FindVariants loads those.
 type ProductRepository interface {
 	FindAll(ctx context.Context, limit, offset int) ([]*models.Product, error)
 	FindByID(ctx context.Context, id string) (*models.Product, error)
+	// FindVariant returns a single variant, or ErrVariantNotFound.
+	FindVariant(ctx context.Context, id string) (*models.ProductVariant, error)
+	// FindVariants returns the variants of the given products, grouped by
+	// product and in display order within each.
+	FindVariants(ctx context.Context, productIDs []string) ([]*models.ProductVariant, error)
+	// FindImages returns the images of the given products and their variants,
+	// primary images first.
+	FindImages(ctx context.Context, productIDs []string) ([]*models.ProductImage, error)
 	// We'll add category methods here as well
 	FindAllCategories(ctx context.Context) ([]*models.Category, error)
 }
 
 type postgresProductRepository struct {
-	db *sql.DB
+	db *instrumentedDB
 }
 
-func NewPostgresProductRepository(db *sql.DB) ProductRepository {
-	return &postgresProductRepository{db: db}
+func NewPostgresProductRepository(pool *pgxpool.Pool) ProductRepository {
+	return &postgresProductRepository{db: instrument(pool, "product")}
 }
 
 func (r *postgresProductRepository) FindAll(ctx context.Context, limit, offset int) ([]*models.Product, error) {
 	query := `
-		SELECT id, category_id, name, description, price, inventory_count, created_at, updated_at
+		SELECT id, category_id, name, description, options, badge_en, badge_fi, created_at, updated_at
 		FROM products
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -40,34 +67,119 @@ func (r *postgresProductRepository) FindAll(ctx context.Context, limit, offset i
 	var products []*models.Product
 	for rows.Next() {
 		p := new(models.Product)
-		if err := rows.Scan(&p.ID, &p.CategoryID, &p.Name, &p.Description, &p.Price, &p.InventoryCount, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.CategoryID, &p.Name, &p.Description, &p.Options, &p.BadgeEN, &p.BadgeFI, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
 	}
 
-	return products, nil
+	return products, rows.Err()
 }
 
 func (r *postgresProductRepository) FindByID(ctx context.Context, id string) (*models.Product, error) {
 	p := new(models.Product)
 	query := `
-		SELECT id, category_id, name, description, price, inventory_count, created_at, updated_at
+		SELECT id, category_id, name, description, options, badge_en, badge_fi, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.CategoryID, &p.Name, &p.Description, &p.Price, &p.InventoryCount, &p.CreatedAt, &p.UpdatedAt,
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.CategoryID, &p.Name, &p.Description, &p.Options, &p.BadgeEN, &p.BadgeFI, &p.CreatedAt, &p.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("product not found")
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProductNotFound
 	}
 	return p, err
 }
 
+func (r *postgresProductRepository) FindVariant(ctx context.Context, id string) (*models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE id = $1`
+	v, err := scanVariant(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVariantNotFound
+	}
+	return v, err
+}
+
+func (r *postgresProductRepository) FindVariants(ctx context.Context, productIDs []string) ([]*models.ProductVariant, error) {
+	query := `
+		SELECT ` + variantColumns + `
+		FROM product_variants
+		WHERE product_id = ANY($1::uuid[])
+		ORDER BY product_id, position, created_at
+	`
+	return queryVariants(ctx, r.db, query, productIDs)
+}
+
+// variantColumns are the columns scanVariant reads, in order.
+const variantColumns = `id, product_id, sku, options, price, inventory_count, position, sale_price, sale_starts_at, sale_ends_at, created_at, updated_at`
+
+func scanVariant(row pgx.Row) (*models.ProductVariant, error) {
+	v := new(models.ProductVariant)
+	var salePrice *float64
+	var saleStartsAt, saleEndsAt *time.Time
+	err := row.Scan(
+		&v.ID, &v.ProductID, &v.SKU, &v.Options, &v.Price, &v.InventoryCount, &v.Position,
+		&salePrice, &saleStartsAt, &saleEndsAt, &v.CreatedAt, &v.UpdatedAt,
+	)
+	v.Sale = saleFrom(salePrice, saleStartsAt, saleEndsAt)
+	return v, err
+}
+
+// saleFrom assembles a sale from the sale_* columns, which
+// product_variants_sale_check keeps all set or all NULL.
+func saleFrom(price *float64, startsAt, endsAt *time.Time) *models.Sale {
+	if price == nil || startsAt == nil {
+		return nil
+	}
+	return &models.Sale{Price: *price, StartsAt: *startsAt, EndsAt: endsAt}
+}
+
+func queryVariants(ctx context.Context, db *instrumentedDB, query string, args ...any) ([]*models.ProductVariant, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []*models.ProductVariant
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+func (r *postgresProductRepository) FindImages(ctx context.Context, productIDs []string) ([]*models.ProductImage, error) {
+	query := `
+		SELECT id, product_id, variant_id, url, alt_text, is_primary, created_at, updated_at
+		FROM product_images
+		WHERE product_id = ANY($1::uuid[])
+		ORDER BY product_id, is_primary DESC, created_at
+	`
+	rows, err := r.db.Query(ctx, query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*models.ProductImage
+	for rows.Next() {
+		img := new(models.ProductImage)
+		if err := rows.Scan(&img.ID, &img.ProductID, &img.VariantID, &img.URL, &img.AltText, &img.IsPrimary, &img.CreatedAt, &img.UpdatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
 func (r *postgresProductRepository) FindAllCategories(ctx context.Context) ([]*models.Category, error) {
 	query := `SELECT id, name, description, created_at, updated_at FROM categories ORDER BY name`
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -81,5 +193,5 @@ func (r *postgresProductRepository) FindAllCategories(ctx context.Context) ([]*m
 		}
 		categories = append(categories, c)
 	}
-	return categories, nil
+	return categories, rows.Err()
 }
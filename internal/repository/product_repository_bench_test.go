@@ -0,0 +1,110 @@
+// backend/internal/repository/product_repository_bench_test.go
+package repository
+
+import (
+	"backend/internal/models"
+	"backend/internal/pgtest"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// The catalog FindAll path before and after moving from database/sql to a
+// native pgx pool. The "before" side opens its own database/sql pool through
+// the pgx stdlib driver, configured as database.Connect used to, so it shares
+// nothing with the pgx pool but the server and schema. They run against the
+// same servers as the other tests (see package pgtest):
+//
+//	go test -run '^$' -bench FindAll ./internal/repository
+
+func BenchmarkFindAll(b *testing.B) {
+	ctx := context.Background()
+	pool := pgtest.New(b)
+	seedBenchProducts(b, pool, 1000)
+
+	db := openStdlibDB(b, pool)
+
+	repo := NewPostgresProductRepository(pool)
+	for _, limit := range []int{20, 100} {
+		b.Run(fmt.Sprintf("database-sql/limit=%d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := findAllStdlib(ctx, db, limit, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("pgxpool/limit=%d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.FindAll(ctx, limit, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// openStdlibDB opens a database/sql pool to the schema pool uses, the way
+// database.Connect did before the move to pgxpool.
+func openStdlibDB(b *testing.B, pool *pgxpool.Pool) *sql.DB {
+	b.Helper()
+	name := stdlib.RegisterConnConfig(pool.Config().ConnConfig)
+	b.Cleanup(func() { stdlib.UnregisterConnConfig(name) })
+
+	db, err := sql.Open("pgx", name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Closed before pgtest drops the schema, like the pool.
+	b.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	if err := db.Ping(); err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+func seedBenchProducts(b *testing.B, pool *pgxpool.Pool, n int) {
+	b.Helper()
+	ctx := context.Background()
+
+	products := make([]*models.Product, n)
+	for i := range products {
+		products[i] = &models.Product{Name: fmt.Sprintf("bench-product-%d", i)}
+		products[i].Description.String, products[i].Description.Valid = "Seeded for benchmarks", true
+	}
+	if _, err := NewPostgresAdminRepository(pool).CreateProducts(ctx, products); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// findAllStdlib is FindAll as it was written against database/sql.
+func findAllStdlib(ctx context.Context, db *sql.DB, limit, offset int) ([]*models.Product, error) {
+	query := `
+		SELECT id, category_id, name, description, created_at, updated_at
+		FROM products
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		p := new(models.Product)
+		if err := rows.Scan(&p.ID, &p.CategoryID, &p.Name, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
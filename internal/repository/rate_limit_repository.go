@@ -0,0 +1,124 @@
+// backend/internal/repository/rate_limit_repository.go
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateLimitRepository stores token buckets. Take refills the bucket for key
+// (capacity tokens, refilling at refillPerSecond) and then removes one token
+// if there is one, as a single atomic step.
+type RateLimitRepository interface {
+	Take(ctx context.Context, key string, capacity int, refillPerSecond float64) (*models.RateLimitBucket, error)
+	// DeleteIdle removes buckets untouched since before; such buckets are full
+	// again, so dropping them changes nothing.
+	DeleteIdle(ctx context.Context, before time.Time) (int64, error)
+}
+
+// --- Postgres ---
+
+type postgresRateLimitRepository struct {
+	db *instrumentedDB
+}
+
+// NewPostgresRateLimitRepository shares buckets between all instances of the API.
+func NewPostgresRateLimitRepository(pool *pgxpool.Pool) RateLimitRepository {
+	return &postgresRateLimitRepository{db: instrument(pool, "rate_limit")}
+}
+
+func (r *postgresRateLimitRepository) Take(ctx context.Context, key string, capacity int, refillPerSecond float64) (*models.RateLimitBucket, error) {
+	// In the UPDATE branch every b.* refers to the row before the update, so
+	// the refilled balance is computed the same way in each SET expression.
+	query := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			allowed = LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM NOW() - b.updated_at), 0) * $3::float8) >= 1,
+			tokens  = LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM NOW() - b.updated_at), 0) * $3::float8)
+			          - CASE WHEN LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM NOW() - b.updated_at), 0) * $3::float8) >= 1 THEN 1 ELSE 0 END,
+			updated_at = NOW()
+		RETURNING tokens, allowed, updated_at`
+	b := &models.RateLimitBucket{Key: key}
+	err := r.db.QueryRow(ctx, query, key, float64(capacity), refillPerSecond).Scan(&b.Tokens, &b.Allowed, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *postgresRateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < $1`
+	res, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// --- In-memory ---
+
+type memoryRateLimitRepository struct {
+	mu      sync.Mutex
+	buckets map[string]models.RateLimitBucket
+}
+
+// NewMemoryRateLimitRepository keeps buckets in process memory; each instance
+// of the API then enforces its own limits.
+func NewMemoryRateLimitRepository() RateLimitRepository {
+	return &memoryRateLimitRepository{buckets: make(map[string]models.RateLimitBucket)}
+}
+
+func (r *memoryRateLimitRepository) Take(ctx context.Context, key string, capacity int, refillPerSecond float64) (*models.RateLimitBucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if len(r.buckets) > memorySweepThreshold {
+		r.sweep(now)
+	}
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = models.RateLimitBucket{Key: key, Tokens: float64(capacity)}
+	} else {
+		elapsed := math.Max(now.Sub(b.UpdatedAt).Seconds(), 0)
+		b.Tokens = math.Min(float64(capacity), b.Tokens+elapsed*refillPerSecond)
+	}
+	b.Allowed = b.Tokens >= 1
+	if b.Allowed {
+		b.Tokens--
+	}
+	b.UpdatedAt = now
+	r.buckets[key] = b
+	return &b, nil
+}
+
+func (r *memoryRateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, b := range r.buckets {
+		if b.UpdatedAt.Before(before) {
+			delete(r.buckets, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// sweep drops buckets idle for an hour. No policy takes that long to refill,
+// so they would be full anyway (config limits periods to an hour). Callers hold mu.
+func (r *memoryRateLimitRepository) sweep(now time.Time) {
+	for key, b := range r.buckets {
+		if now.Sub(b.UpdatedAt) > time.Hour {
+			delete(r.buckets, key)
+		}
+	}
+}
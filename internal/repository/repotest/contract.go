@@ -0,0 +1,940 @@
+// backend/internal/repository/repotest/contract.go
+package repotest
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Repositories is one implementation of the repositories under contract.
+// They must share a store, so a user from Users can own a cart in Store.
+type Repositories struct {
+	Users    repository.UserRepository
+	Products repository.ProductRepository
+	Store    repository.StoreRepository
+	Admin    repository.AdminRepository
+	Prices   repository.PriceRepository
+	// AddCategory seeds a category and returns its ID; no repository creates them.
+	AddCategory func(t *testing.T, name string) string
+}
+
+// missingID is a well-formed UUID that no row has.
+const missingID = "00000000-0000-4000-8000-000000000000"
+
+// RunContract checks that an implementation behaves like the Postgres
+// repositories the services are written against. newRepos is called once
+// per subtest and must return repositories over an empty store.
+func RunContract(t *testing.T, newRepos func(t *testing.T) Repositories) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r Repositories)
+	}{
+		{"UserCreateAndFind", testUserCreateAndFind},
+		{"UserDuplicateEmail", testUserDuplicateEmail},
+		{"UserNotFound", testUserNotFound},
+		{"UserUpdateProfile", testUserUpdateProfile},
+		{"UserUpdatePassword", testUserUpdatePassword},
+		{"UserMarkEmailVerified", testUserMarkEmailVerified},
+		{"UserDeleteCascadesCart", testUserDeleteCascadesCart},
+		{"ProductCreateAndFind", testProductCreateAndFind},
+		{"ProductFindAllPages", testProductFindAllPages},
+		{"ProductBulkCreate", testProductBulkCreate},
+		{"ProductUpdateAndDelete", testProductUpdateAndDelete},
+		{"ProductAdjustInventory", testProductAdjustInventory},
+		{"VariantCreateAndFind", testVariantCreateAndFind},
+		{"VariantDuplicates", testVariantDuplicates},
+		{"VariantUpdateAndDelete", testVariantUpdateAndDelete},
+		{"VariantAdjustInventory", testVariantAdjustInventory},
+		{"PriceSetPrice", testPriceSetPrice},
+		{"PriceSales", testPriceSales},
+		{"PriceScheduledChanges", testPriceScheduledChanges},
+		{"PriceHistory", testPriceHistory},
+		{"PriceHistoryOutlivesVariant", testPriceHistoryOutlivesVariant},
+		{"CategoriesByName", testCategoriesByName},
+		{"CartUpsertAddsQuantities", testCartUpsertAddsQuantities},
+		{"CartRemoveAndClear", testCartRemoveAndClear},
+		{"CartUnknownProduct", testCartUnknownProduct},
+		{"CartConcurrentUpserts", testCartConcurrentUpserts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepos(t))
+		})
+	}
+}
+
+func mustCreateUser(t *testing.T, r Repositories, email string) *models.User {
+	t.Helper()
+	u := &models.User{FullName: "Test User", Email: email, PasswordHash: "hash"}
+	if err := r.Users.Create(context.Background(), u); err != nil {
+		t.Fatalf("creating user %s: %v", email, err)
+	}
+	return u
+}
+
+// mustCreateProduct creates a product with a single variant, p.Variants[0],
+// whose SKU is the product's name.
+func mustCreateProduct(t *testing.T, r Repositories, name string, price float64, inventory int) *models.Product {
+	t.Helper()
+	p := &models.Product{Name: name}
+	if err := r.Admin.CreateProduct(context.Background(), p); err != nil {
+		t.Fatalf("creating product %s: %v", name, err)
+	}
+	v := &models.ProductVariant{ProductID: p.ID, SKU: name, Price: price, InventoryCount: inventory}
+	if err := r.Admin.CreateVariants(context.Background(), []*models.ProductVariant{v}); err != nil {
+		t.Fatalf("creating the variant of %s: %v", name, err)
+	}
+	p.Variants = []*models.ProductVariant{v}
+	return p
+}
+
+func testUserCreateAndFind(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+	if u.ID == "" || u.CreatedAt.IsZero() || u.UpdatedAt.IsZero() {
+		t.Fatalf("Create did not fill in generated fields: %+v", u)
+	}
+
+	got, err := r.Users.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Email != u.Email || got.FullName != u.FullName || got.PasswordHash != u.PasswordHash {
+		t.Errorf("FindByID = %+v, want the created user", got)
+	}
+	if got.PreferredLanguage != models.LanguageEN || got.TokenVersion != 0 || got.EmailVerifiedAt != nil || got.Role != nil {
+		t.Errorf("new user defaults: language %q, token version %d, verified %v, role %v",
+			got.PreferredLanguage, got.TokenVersion, got.EmailVerifiedAt, got.Role)
+	}
+
+	byEmail, err := r.Users.FindByEmail(ctx, "JANE@Example.com")
+	if err != nil {
+		t.Fatalf("FindByEmail ignoring case: %v", err)
+	}
+	if byEmail.ID != u.ID {
+		t.Errorf("FindByEmail returned %s, want %s", byEmail.ID, u.ID)
+	}
+}
+
+func testUserDuplicateEmail(t *testing.T, r Repositories) {
+	mustCreateUser(t, r, "jane@example.com")
+	dup := &models.User{FullName: "Other", Email: "Jane@Example.com", PasswordHash: "hash"}
+	if err := r.Users.Create(context.Background(), dup); err == nil {
+		t.Error("Create accepted an email that differs only in case")
+	}
+}
+
+func testUserNotFound(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	checks := map[string]error{}
+	_, checks["FindByID"] = r.Users.FindByID(ctx, missingID)
+	_, checks["FindByEmail"] = r.Users.FindByEmail(ctx, "nobody@example.com")
+	checks["UpdateProfile"] = r.Users.UpdateProfile(ctx, &models.User{ID: missingID, FullName: "x", PreferredLanguage: models.LanguageEN})
+	_, checks["UpdatePassword"] = r.Users.UpdatePassword(ctx, missingID, "hash")
+	checks["MarkEmailVerified"] = r.Users.MarkEmailVerified(ctx, missingID)
+	checks["Delete"] = r.Users.Delete(ctx, missingID)
+
+	for method, err := range checks {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			t.Errorf("%s: err = %v, want ErrUserNotFound", method, err)
+		}
+	}
+}
+
+func testUserUpdateProfile(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+	created := u.UpdatedAt
+
+	phone := "+358 40 123 4567"
+	u.FullName, u.Phone, u.PreferredLanguage = "Jane Doe", &phone, models.LanguageFI
+	if err := r.Users.UpdateProfile(ctx, u); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if u.UpdatedAt.Before(created) {
+		t.Errorf("UpdatedAt went backwards: %v < %v", u.UpdatedAt, created)
+	}
+
+	got, err := r.Users.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.FullName != "Jane Doe" || got.Phone == nil || *got.Phone != phone || got.PreferredLanguage != models.LanguageFI {
+		t.Errorf("after UpdateProfile: name %q, phone %v, language %q", got.FullName, got.Phone, got.PreferredLanguage)
+	}
+
+	u.Phone = nil
+	if err := r.Users.UpdateProfile(ctx, u); err != nil {
+		t.Fatalf("UpdateProfile clearing phone: %v", err)
+	}
+	if got, _ := r.Users.FindByID(ctx, u.ID); got.Phone != nil {
+		t.Errorf("phone = %q, want cleared", *got.Phone)
+	}
+}
+
+func testUserUpdatePassword(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+
+	for want := 1; want <= 2; want++ {
+		version, err := r.Users.UpdatePassword(ctx, u.ID, fmt.Sprintf("hash-%d", want))
+		if err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		if version != want {
+			t.Errorf("token version = %d, want %d", version, want)
+		}
+	}
+
+	got, _ := r.Users.FindByID(ctx, u.ID)
+	if got.PasswordHash != "hash-2" || got.TokenVersion != 2 {
+		t.Errorf("stored hash %q, version %d; want hash-2, 2", got.PasswordHash, got.TokenVersion)
+	}
+}
+
+func testUserMarkEmailVerified(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+
+	if err := r.Users.MarkEmailVerified(ctx, u.ID); err != nil {
+		t.Fatalf("MarkEmailVerified: %v", err)
+	}
+	first, _ := r.Users.FindByID(ctx, u.ID)
+	if first.EmailVerifiedAt == nil {
+		t.Fatal("EmailVerifiedAt not set")
+	}
+
+	// Verifying again keeps the original time.
+	if err := r.Users.MarkEmailVerified(ctx, u.ID); err != nil {
+		t.Fatalf("MarkEmailVerified again: %v", err)
+	}
+	second, _ := r.Users.FindByID(ctx, u.ID)
+	if second.EmailVerifiedAt == nil || !second.EmailVerifiedAt.Equal(*first.EmailVerifiedAt) {
+		t.Errorf("EmailVerifiedAt changed from %v to %v", first.EmailVerifiedAt, second.EmailVerifiedAt)
+	}
+}
+
+func testUserDeleteCascadesCart(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+	if err := r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: p.Variants[0].ID, Quantity: 1}); err != nil {
+		t.Fatalf("UpsertCartItem: %v", err)
+	}
+
+	if err := r.Users.Delete(ctx, u.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := r.Users.FindByID(ctx, u.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("FindByID after Delete: err = %v, want ErrUserNotFound", err)
+	}
+	items, err := r.Store.FindCartByUser(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindCartByUser: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("deleted user still has %d cart items", len(items))
+	}
+}
+
+func testProductCreateAndFind(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	categoryID := r.AddCategory(t, "Drinks")
+
+	p := &models.Product{Name: "Coffee", Options: []string{"size", "grind"}}
+	p.CategoryID.String, p.CategoryID.Valid = categoryID, true
+	p.Description.String, p.Description.Valid = "Dark roast", true
+	p.BadgeEN.String, p.BadgeEN.Valid = "New", true
+	if err := r.Admin.CreateProduct(ctx, p); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	if p.ID == "" || p.CreatedAt.IsZero() {
+		t.Fatalf("CreateProduct did not fill in generated fields: %+v", p)
+	}
+
+	got, err := r.Products.FindByID(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Name != p.Name || fmt.Sprint(got.Options) != fmt.Sprint(p.Options) ||
+		got.CategoryID != p.CategoryID || got.Description != p.Description || got.BadgeEN != p.BadgeEN || got.BadgeFI.Valid {
+		t.Errorf("FindByID = %+v, want %+v", got, p)
+	}
+
+	if _, err := r.Products.FindByID(ctx, missingID); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Errorf("FindByID(missing): err = %v, want ErrProductNotFound", err)
+	}
+}
+
+func testProductFindAllPages(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		mustCreateProduct(t, r, fmt.Sprintf("Product %d", i), 1, 1)
+	}
+
+	seen := make(map[string]bool)
+	var last time.Time
+	for offset := 0; offset < 6; offset += 2 {
+		page, err := r.Products.FindAll(ctx, 2, offset)
+		if err != nil {
+			t.Fatalf("FindAll(2, %d): %v", offset, err)
+		}
+		if want := min(2, 5-offset); len(page) != want {
+			t.Fatalf("FindAll(2, %d) returned %d products, want %d", offset, len(page), want)
+		}
+		for _, p := range page {
+			if seen[p.ID] {
+				t.Errorf("%s appears on more than one page", p.Name)
+			}
+			seen[p.ID] = true
+			if !last.IsZero() && p.CreatedAt.After(last) {
+				t.Errorf("%s is out of order: newest must come first", p.Name)
+			}
+			last = p.CreatedAt
+		}
+	}
+
+	if page, err := r.Products.FindAll(ctx, 2, 10); err != nil || len(page) != 0 {
+		t.Errorf("FindAll past the end = %d products, %v; want none", len(page), err)
+	}
+}
+
+func testProductBulkCreate(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	products := make([]*models.Product, 3)
+	for i := range products {
+		products[i] = &models.Product{Name: fmt.Sprintf("Bulk %d", i)}
+	}
+
+	n, err := r.Admin.CreateProducts(ctx, products)
+	if err != nil {
+		t.Fatalf("CreateProducts: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("CreateProducts = %d, want 3", n)
+	}
+	for _, p := range products {
+		if p.ID == "" {
+			t.Fatalf("CreateProducts did not fill in the ID of %s", p.Name)
+		}
+	}
+	all, err := r.Products.FindAll(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("FindAll after bulk create = %d products, want 3", len(all))
+	}
+}
+
+func testProductUpdateAndDelete(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+
+	p.Name = "Decaf"
+	p.Description.String, p.Description.Valid = "No caffeine", true
+	p.BadgeFI.String, p.BadgeFI.Valid = "Uutuus", true
+	if err := r.Admin.UpdateProduct(ctx, p); err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	got, err := r.Admin.FindProductForUpdate(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindProductForUpdate: %v", err)
+	}
+	if got.Name != "Decaf" || got.Description != p.Description || got.BadgeFI != p.BadgeFI {
+		t.Errorf("after UpdateProduct: %q, %v, %v", got.Name, got.Description, got.BadgeFI)
+	}
+	if len(got.Variants) != 1 || got.Variants[0].ID != p.Variants[0].ID {
+		t.Errorf("FindProductForUpdate returned variants %+v, want the one created", got.Variants)
+	}
+
+	u := mustCreateUser(t, r, "jane@example.com")
+	if err := r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: p.Variants[0].ID, Quantity: 2}); err != nil {
+		t.Fatalf("UpsertCartItem: %v", err)
+	}
+	if err := r.Admin.DeleteProduct(ctx, p.ID); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	if _, err := r.Products.FindByID(ctx, p.ID); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Errorf("FindByID after DeleteProduct: err = %v, want ErrProductNotFound", err)
+	}
+	if items, _ := r.Store.FindCartByUser(ctx, u.ID); len(items) != 0 {
+		t.Errorf("deleted product is still in %d carts", len(items))
+	}
+	if variants, _ := r.Products.FindVariants(ctx, []string{p.ID}); len(variants) != 0 {
+		t.Errorf("deleted product still has %d variants", len(variants))
+	}
+
+	checks := map[string]error{}
+	checks["UpdateProduct"] = r.Admin.UpdateProduct(ctx, &models.Product{ID: missingID, Name: "x"})
+	checks["DeleteProduct"] = r.Admin.DeleteProduct(ctx, missingID)
+	_, checks["FindProductForUpdate"] = r.Admin.FindProductForUpdate(ctx, missingID)
+	for method, err := range checks {
+		if !errors.Is(err, repository.ErrProductNotFound) {
+			t.Errorf("%s(missing): err = %v, want ErrProductNotFound", method, err)
+		}
+	}
+}
+
+func testProductAdjustInventory(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+
+	for _, step := range []struct{ change, want int }{{5, 15}, {-12, 3}} {
+		got, err := r.Admin.AdjustProductInventory(ctx, p.ID, step.change)
+		if err != nil {
+			t.Fatalf("AdjustProductInventory(%d): %v", step.change, err)
+		}
+		if got != step.want {
+			t.Errorf("AdjustProductInventory(%d) = %d, want %d", step.change, got, step.want)
+		}
+	}
+
+	if _, err := r.Admin.AdjustProductInventory(ctx, missingID, 1); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Errorf("AdjustProductInventory(missing): err = %v, want ErrProductNotFound", err)
+	}
+
+	second := &models.ProductVariant{ProductID: p.ID, SKU: "Coffee 2", Options: map[string]string{"size": "1 kg"}}
+	if err := r.Admin.CreateVariants(ctx, []*models.ProductVariant{second}); err != nil {
+		t.Fatalf("CreateVariants: %v", err)
+	}
+	if _, err := r.Admin.AdjustProductInventory(ctx, p.ID, 1); !errors.Is(err, repository.ErrMultipleVariants) {
+		t.Errorf("AdjustProductInventory with two variants: err = %v, want ErrMultipleVariants", err)
+	}
+}
+
+func mustCreateVariants(t *testing.T, r Repositories, variants ...*models.ProductVariant) {
+	t.Helper()
+	if err := r.Admin.CreateVariants(context.Background(), variants); err != nil {
+		t.Fatalf("CreateVariants: %v", err)
+	}
+}
+
+func testVariantCreateAndFind(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	coffee := &models.Product{Name: "Coffee", Options: []string{"size"}}
+	tea := &models.Product{Name: "Tea"}
+	for _, p := range []*models.Product{coffee, tea} {
+		if err := r.Admin.CreateProduct(ctx, p); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	large := &models.ProductVariant{ProductID: coffee.ID, SKU: "COF-1000", Options: map[string]string{"size": "1 kg"}, Price: 22, InventoryCount: 4, Position: 1}
+	small := &models.ProductVariant{ProductID: coffee.ID, SKU: "COF-500", Options: map[string]string{"size": "500 g"}, Price: 12.5, InventoryCount: 10}
+	mustCreateVariants(t, r, large, small, &models.ProductVariant{ProductID: tea.ID, SKU: "TEA", Price: 4})
+	if large.ID == "" || small.ID == "" || large.CreatedAt.IsZero() {
+		t.Fatalf("CreateVariants did not fill in generated fields: %+v", large)
+	}
+
+	variants, err := r.Products.FindVariants(ctx, []string{coffee.ID})
+	if err != nil {
+		t.Fatalf("FindVariants: %v", err)
+	}
+	if len(variants) != 2 || variants[0].ID != small.ID || variants[1].ID != large.ID {
+		t.Fatalf("FindVariants = %+v, want small then large", variants)
+	}
+	got := variants[1]
+	if got.ProductID != coffee.ID || got.SKU != "COF-1000" || got.Options["size"] != "1 kg" || got.Price != 22 || got.InventoryCount != 4 || got.Position != 1 {
+		t.Errorf("stored variant = %+v, want %+v", got, large)
+	}
+
+	if v, err := r.Products.FindVariant(ctx, large.ID); err != nil || v.SKU != "COF-1000" || v.ProductID != coffee.ID {
+		t.Errorf("FindVariant = %+v, %v; want COF-1000", v, err)
+	}
+	if _, err := r.Products.FindVariant(ctx, missingID); !errors.Is(err, repository.ErrVariantNotFound) {
+		t.Errorf("FindVariant(missing) err = %v, want ErrVariantNotFound", err)
+	}
+
+	all, _ := r.Products.FindVariants(ctx, []string{coffee.ID, tea.ID, missingID})
+	if len(all) != 3 {
+		t.Errorf("FindVariants for both products = %d variants, want 3", len(all))
+	}
+
+	if err := r.Admin.CreateVariants(ctx, []*models.ProductVariant{{ProductID: missingID, SKU: "X"}}); err == nil {
+		t.Error("CreateVariants accepted a product that doesn't exist")
+	}
+}
+
+func testVariantDuplicates(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+	other := mustCreateProduct(t, r, "Tea", 4, 10)
+
+	dupSKU := &models.ProductVariant{ProductID: other.ID, SKU: "Coffee", Options: map[string]string{"size": "1 kg"}}
+	if err := r.Admin.CreateVariants(ctx, []*models.ProductVariant{dupSKU}); !errors.Is(err, repository.ErrDuplicateSKU) {
+		t.Errorf("CreateVariants with a taken SKU: err = %v, want ErrDuplicateSKU", err)
+	}
+	// The default variants of both products have no options, which only
+	// clashes within one product.
+	dupOptions := &models.ProductVariant{ProductID: p.ID, SKU: "Coffee 2"}
+	if err := r.Admin.CreateVariants(ctx, []*models.ProductVariant{dupOptions}); !errors.Is(err, repository.ErrDuplicateVariant) {
+		t.Errorf("CreateVariants with taken options: err = %v, want ErrDuplicateVariant", err)
+	}
+
+	// A batch is all or nothing.
+	ok := &models.ProductVariant{ProductID: p.ID, SKU: "Coffee 3", Options: map[string]string{"size": "1 kg"}}
+	if err := r.Admin.CreateVariants(ctx, []*models.ProductVariant{ok, dupSKU}); err == nil {
+		t.Error("CreateVariants accepted a batch with a taken SKU")
+	}
+	if variants, _ := r.Products.FindVariants(ctx, []string{p.ID}); len(variants) != 1 {
+		t.Errorf("a failed batch left %d variants, want 1", len(variants))
+	}
+
+	mustCreateVariants(t, r, ok)
+	ok.SKU = "Tea"
+	if err := r.Admin.UpdateVariant(ctx, ok); !errors.Is(err, repository.ErrDuplicateSKU) {
+		t.Errorf("UpdateVariant to a taken SKU: err = %v, want ErrDuplicateSKU", err)
+	}
+	ok.SKU, ok.Options = "Coffee 3", nil
+	if err := r.Admin.UpdateVariant(ctx, ok); !errors.Is(err, repository.ErrDuplicateVariant) {
+		t.Errorf("UpdateVariant to taken options: err = %v, want ErrDuplicateVariant", err)
+	}
+}
+
+func testVariantUpdateAndDelete(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+	v := p.Variants[0]
+	created := v.UpdatedAt
+
+	v.SKU, v.Options, v.Price, v.Position, v.InventoryCount = "COF-500", map[string]string{"size": "500 g"}, 11.75, 2, 99
+	if err := r.Admin.UpdateVariant(ctx, v); err != nil {
+		t.Fatalf("UpdateVariant: %v", err)
+	}
+	if v.InventoryCount != 3 || v.Price != 12.5 || v.UpdatedAt.Before(created) {
+		t.Errorf("UpdateVariant must leave the stock and price alone: inventory %d, price %v, updated %v", v.InventoryCount, v.Price, v.UpdatedAt)
+	}
+	got, err := r.Admin.FindVariantForUpdate(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("FindVariantForUpdate: %v", err)
+	}
+	if got.SKU != "COF-500" || got.Options["size"] != "500 g" || got.Price != 12.5 || got.Position != 2 || got.InventoryCount != 3 {
+		t.Errorf("after UpdateVariant: %+v", got)
+	}
+
+	u := mustCreateUser(t, r, "jane@example.com")
+	if err := r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: v.ID, Quantity: 2}); err != nil {
+		t.Fatalf("UpsertCartItem: %v", err)
+	}
+	items, _ := r.Store.FindCartByUser(ctx, u.ID)
+	if len(items) != 1 || items[0].ProductID != p.ID || items[0].SKU != "COF-500" || items[0].Options["size"] != "500 g" {
+		t.Errorf("cart line = %+v, want the updated variant", items)
+	}
+
+	if err := r.Admin.DeleteVariant(ctx, v.ID); err != nil {
+		t.Fatalf("DeleteVariant: %v", err)
+	}
+	if items, _ := r.Store.FindCartByUser(ctx, u.ID); len(items) != 0 {
+		t.Errorf("deleted variant is still in %d carts", len(items))
+	}
+	if _, err := r.Products.FindByID(ctx, p.ID); err != nil {
+		t.Errorf("deleting a variant must keep the product: %v", err)
+	}
+
+	checks := map[string]error{}
+	_, checks["FindVariantForUpdate"] = r.Admin.FindVariantForUpdate(ctx, v.ID)
+	checks["UpdateVariant"] = r.Admin.UpdateVariant(ctx, v)
+	checks["DeleteVariant"] = r.Admin.DeleteVariant(ctx, v.ID)
+	_, checks["AdjustVariantInventory"] = r.Admin.AdjustVariantInventory(ctx, v.ID, 1)
+	for method, err := range checks {
+		if !errors.Is(err, repository.ErrVariantNotFound) {
+			t.Errorf("%s(missing): err = %v, want ErrVariantNotFound", method, err)
+		}
+	}
+}
+
+func testVariantAdjustInventory(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+	v := p.Variants[0]
+
+	for _, step := range []struct{ change, want int }{{5, 15}, {-12, 3}} {
+		got, err := r.Admin.AdjustVariantInventory(ctx, v.ID, step.change)
+		if err != nil {
+			t.Fatalf("AdjustVariantInventory(%d): %v", step.change, err)
+		}
+		if got != step.want {
+			t.Errorf("AdjustVariantInventory(%d) = %d, want %d", step.change, got, step.want)
+		}
+	}
+}
+
+func testPriceSetPrice(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+	v := p.Variants[0]
+
+	previous, err := r.Prices.SetPrice(ctx, v.ID, 14)
+	if err != nil {
+		t.Fatalf("SetPrice: %v", err)
+	}
+	if previous != 12.5 {
+		t.Errorf("SetPrice returned %v, want the old price 12.5", previous)
+	}
+	got, err := r.Admin.FindVariantForUpdate(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("FindVariantForUpdate: %v", err)
+	}
+	if got.Price != 14 || got.InventoryCount != 3 || !got.UpdatedAt.After(v.UpdatedAt) {
+		t.Errorf("after SetPrice: %+v", got)
+	}
+
+	if _, err := r.Prices.SetPrice(ctx, missingID, 1); !errors.Is(err, repository.ErrVariantNotFound) {
+		t.Errorf("SetPrice(missing): err = %v, want ErrVariantNotFound", err)
+	}
+}
+
+func testPriceSales(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+	v := p.Variants[0]
+	u := mustCreateUser(t, r, "jane@example.com")
+	if err := r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: v.ID, Quantity: 2}); err != nil {
+		t.Fatalf("UpsertCartItem: %v", err)
+	}
+	cartLine := func() *models.CartItemDetail {
+		t.Helper()
+		items, err := r.Store.FindCartByUser(ctx, u.ID)
+		if err != nil || len(items) != 1 {
+			t.Fatalf("FindCartByUser = %v, %v", items, err)
+		}
+		return items[0]
+	}
+
+	now := time.Now().Truncate(time.Second)
+	ends := now.Add(time.Hour)
+	sale := &models.Sale{Price: 9.99, StartsAt: now.Add(-time.Hour), EndsAt: &ends}
+	if err := r.Prices.SetSale(ctx, v.ID, sale); err != nil {
+		t.Fatalf("SetSale: %v", err)
+	}
+	variants, _ := r.Products.FindVariants(ctx, []string{p.ID})
+	if len(variants) != 1 || variants[0].Sale == nil {
+		t.Fatalf("FindVariants after SetSale = %+v, want the variant on sale", variants)
+	}
+	if got := variants[0].Sale; got.Price != 9.99 || !got.StartsAt.Equal(sale.StartsAt) || got.EndsAt == nil || !got.EndsAt.Equal(ends) {
+		t.Errorf("stored sale = %+v, want %+v", got, sale)
+	}
+	if line := cartLine(); line.PricePerUnit != 9.99 || line.RegularPrice != 12.5 || !line.OnSale || line.LineItemTotal != 19.98 {
+		t.Errorf("cart line during the sale = %+v", line)
+	}
+
+	for _, tt := range []struct {
+		from, to time.Time
+		want     bool
+	}{
+		{now.Add(-2 * time.Hour), now, true},                // The start
+		{now.Add(-30 * time.Minute), now, false},            // Neither
+		{now, ends, true},                                   // The end, inclusive
+		{ends, now.Add(2 * time.Hour), false},               // The end, exclusive
+		{now.Add(-time.Hour), now.Add(-time.Minute), false}, // The start, exclusive
+	} {
+		got, err := r.Prices.SalesChangedBetween(ctx, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("SalesChangedBetween: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("SalesChangedBetween(%v, %v) = %v, want %v", tt.from.Sub(now), tt.to.Sub(now), got, tt.want)
+		}
+	}
+
+	// A sale that hasn't started doesn't change the price yet.
+	sale.StartsAt, sale.EndsAt = now.Add(time.Hour), nil
+	if err := r.Prices.SetSale(ctx, v.ID, sale); err != nil {
+		t.Fatalf("SetSale: %v", err)
+	}
+	if line := cartLine(); line.PricePerUnit != 12.5 || line.OnSale {
+		t.Errorf("cart line before the sale = %+v", line)
+	}
+
+	if err := r.Prices.SetSale(ctx, v.ID, nil); err != nil {
+		t.Fatalf("SetSale(nil): %v", err)
+	}
+	if variants, _ := r.Products.FindVariants(ctx, []string{p.ID}); variants[0].Sale != nil {
+		t.Errorf("ended sale is still stored: %+v", variants[0].Sale)
+	}
+	if err := r.Prices.SetSale(ctx, missingID, nil); !errors.Is(err, repository.ErrVariantNotFound) {
+		t.Errorf("SetSale(missing): err = %v, want ErrVariantNotFound", err)
+	}
+}
+
+func testPriceScheduledChanges(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+	v := p.Variants[0]
+	admin := "admin-1"
+
+	now := time.Now().Truncate(time.Second)
+	later := &models.ScheduledPriceChange{VariantID: v.ID, Price: 15, EffectiveAt: now.Add(2 * time.Hour), CreatedBy: &admin}
+	soon := &models.ScheduledPriceChange{VariantID: v.ID, Price: 14, EffectiveAt: now.Add(-time.Minute)}
+	earliest := &models.ScheduledPriceChange{VariantID: v.ID, Price: 13, EffectiveAt: now.Add(-time.Hour)}
+	for _, c := range []*models.ScheduledPriceChange{later, soon, earliest} {
+		if err := r.Prices.SchedulePriceChange(ctx, c); err != nil {
+			t.Fatalf("SchedulePriceChange: %v", err)
+		}
+		if c.ID == "" || c.CreatedAt.IsZero() {
+			t.Fatalf("SchedulePriceChange did not fill in generated fields: %+v", c)
+		}
+	}
+
+	ids := func(changes []*models.ScheduledPriceChange) []string {
+		var ids []string
+		for _, c := range changes {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	pending, err := r.Prices.FindPendingChanges(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("FindPendingChanges: %v", err)
+	}
+	if fmt.Sprint(ids(pending)) != fmt.Sprint([]string{earliest.ID, soon.ID, later.ID}) {
+		t.Fatalf("FindPendingChanges = %v, want soonest first", ids(pending))
+	}
+	if got := pending[2]; got.Price != 15 || !got.EffectiveAt.Equal(later.EffectiveAt) || got.CreatedBy == nil || *got.CreatedBy != admin || got.AppliedAt != nil {
+		t.Errorf("stored change = %+v, want %+v", got, later)
+	}
+
+	due, err := r.Prices.FindDueChanges(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("FindDueChanges: %v", err)
+	}
+	if fmt.Sprint(ids(due)) != fmt.Sprint([]string{earliest.ID, soon.ID}) {
+		t.Errorf("FindDueChanges = %v, want the two past changes, oldest first", ids(due))
+	}
+	if due, _ := r.Prices.FindDueChanges(ctx, now, 1); len(due) != 1 || due[0].ID != earliest.ID {
+		t.Errorf("FindDueChanges with limit 1 = %v, want the oldest", ids(due))
+	}
+
+	if err := r.Prices.MarkApplied(ctx, earliest.ID); err != nil {
+		t.Fatalf("MarkApplied: %v", err)
+	}
+	if err := r.Prices.MarkApplied(ctx, earliest.ID); !errors.Is(err, repository.ErrPriceChangeNotFound) {
+		t.Errorf("MarkApplied twice: err = %v, want ErrPriceChangeNotFound", err)
+	}
+	if due, _ := r.Prices.FindDueChanges(ctx, now, 10); len(due) != 1 || due[0].ID != soon.ID {
+		t.Errorf("FindDueChanges after MarkApplied = %v, want only the other past change", ids(due))
+	}
+
+	if _, err := r.Prices.CancelPriceChange(ctx, earliest.ID); !errors.Is(err, repository.ErrPriceChangeNotFound) {
+		t.Errorf("CancelPriceChange(applied): err = %v, want ErrPriceChangeNotFound", err)
+	}
+	cancelled, err := r.Prices.CancelPriceChange(ctx, later.ID)
+	if err != nil {
+		t.Fatalf("CancelPriceChange: %v", err)
+	}
+	if cancelled.ID != later.ID || cancelled.Price != 15 {
+		t.Errorf("CancelPriceChange returned %+v, want %+v", cancelled, later)
+	}
+	if pending, _ := r.Prices.FindPendingChanges(ctx, v.ID); len(pending) != 1 || pending[0].ID != soon.ID {
+		t.Errorf("FindPendingChanges after cancelling = %v, want only %s", ids(pending), soon.ID)
+	}
+
+	orphan := &models.ScheduledPriceChange{VariantID: missingID, Price: 1, EffectiveAt: now}
+	if err := r.Prices.SchedulePriceChange(ctx, orphan); !errors.Is(err, repository.ErrVariantNotFound) {
+		t.Errorf("SchedulePriceChange(missing variant): err = %v, want ErrVariantNotFound", err)
+	}
+
+	if err := r.Admin.DeleteVariant(ctx, v.ID); err != nil {
+		t.Fatalf("DeleteVariant: %v", err)
+	}
+	if due, _ := r.Prices.FindDueChanges(ctx, now, 10); len(due) != 0 {
+		t.Errorf("deleted variant still has %d due changes", len(due))
+	}
+}
+
+func testPriceHistory(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+	v := p.Variants[0]
+	admin := "admin-1"
+
+	scheduled := &models.ScheduledPriceChange{VariantID: v.ID, Price: 13, EffectiveAt: time.Now()}
+	if err := r.Prices.SchedulePriceChange(ctx, scheduled); err != nil {
+		t.Fatalf("SchedulePriceChange: %v", err)
+	}
+	manual := &models.PriceChange{VariantID: v.ID, OldPrice: 12.5, NewPrice: 14, Source: models.PriceSourceAdmin, ChangedBy: &admin}
+	automatic := &models.PriceChange{VariantID: v.ID, OldPrice: 14, NewPrice: 13, Source: models.PriceSourceSchedule, ScheduledChangeID: &scheduled.ID}
+	for _, c := range []*models.PriceChange{manual, automatic} {
+		if err := r.Prices.AppendHistory(ctx, c); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+		if c.ID == "" || c.ChangedAt.IsZero() {
+			t.Fatalf("AppendHistory did not fill in generated fields: %+v", c)
+		}
+	}
+
+	history, err := r.Prices.FindHistory(ctx, v.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("FindHistory: %v", err)
+	}
+	if len(history) != 2 || history[0].ID != automatic.ID || history[1].ID != manual.ID {
+		t.Fatalf("FindHistory = %+v, want newest first", history)
+	}
+	if got := history[0]; got.OldPrice != 14 || got.NewPrice != 13 || got.Source != models.PriceSourceSchedule ||
+		got.ChangedBy != nil || got.ScheduledChangeID == nil || *got.ScheduledChangeID != scheduled.ID {
+		t.Errorf("scheduled history entry = %+v", got)
+	}
+	if got := history[1]; got.Source != models.PriceSourceAdmin || got.ChangedBy == nil || *got.ChangedBy != admin || got.ScheduledChangeID != nil {
+		t.Errorf("manual history entry = %+v", got)
+	}
+	if page, _ := r.Prices.FindHistory(ctx, v.ID, 1, 1); len(page) != 1 || page[0].ID != manual.ID {
+		t.Errorf("FindHistory(limit 1, offset 1) = %+v, want the older entry", page)
+	}
+
+	// The history outlives the variant; its scheduled changes don't.
+	if err := r.Admin.DeleteProduct(ctx, p.ID); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	history, err = r.Prices.FindHistory(ctx, v.ID, 10, 0)
+	if err != nil || len(history) != 2 || history[0].ScheduledChangeID != nil {
+		t.Errorf("FindHistory after deleting the variant = %+v, %v; want both entries without the scheduled change", history, err)
+	}
+	if pending, _ := r.Prices.FindPendingChanges(ctx, v.ID); len(pending) != 0 {
+		t.Errorf("FindPendingChanges after deleting the variant = %+v, want none", pending)
+	}
+}
+
+func testPriceHistoryOutlivesVariant(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 3)
+	v := p.Variants[0]
+	change := &models.PriceChange{VariantID: v.ID, OldPrice: 12.5, NewPrice: 14, Source: models.PriceSourceAdmin}
+	if err := r.Prices.AppendHistory(ctx, change); err != nil {
+		t.Fatalf("AppendHistory: %v", err)
+	}
+
+	if err := r.Admin.DeleteVariant(ctx, v.ID); err != nil {
+		t.Fatalf("DeleteVariant: %v", err)
+	}
+	if _, err := r.Products.FindVariant(ctx, v.ID); !errors.Is(err, repository.ErrVariantNotFound) {
+		t.Fatalf("FindVariant after deleting = %v, want ErrVariantNotFound", err)
+	}
+	history, err := r.Prices.FindHistory(ctx, v.ID, 10, 0)
+	if err != nil || len(history) != 1 || history[0].ID != change.ID || history[0].NewPrice != 14 {
+		t.Errorf("FindHistory after deleting the variant = %+v, %v; want the change still listed", history, err)
+	}
+}
+
+func testCategoriesByName(t *testing.T, r Repositories) {
+	for _, name := range []string{"Tea", "Coffee", "Snacks"} {
+		r.AddCategory(t, name)
+	}
+	categories, err := r.Products.FindAllCategories(context.Background())
+	if err != nil {
+		t.Fatalf("FindAllCategories: %v", err)
+	}
+	var names []string
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	if fmt.Sprint(names) != "[Coffee Snacks Tea]" {
+		t.Errorf("categories = %v, want them sorted by name", names)
+	}
+}
+
+func testCartUpsertAddsQuantities(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+	other := mustCreateUser(t, r, "john@example.com")
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+
+	for _, qty := range []int{2, 3} {
+		if err := r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: p.Variants[0].ID, Quantity: qty}); err != nil {
+			t.Fatalf("UpsertCartItem: %v", err)
+		}
+	}
+
+	items, err := r.Store.FindCartByUser(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindCartByUser: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("cart has %d lines, want 1", len(items))
+	}
+	item := items[0]
+	if item.VariantID != p.Variants[0].ID || item.ProductID != p.ID || item.SKU != "Coffee" || item.Quantity != 5 || item.ProductName != "Coffee" || item.PricePerUnit != 12.5 || item.LineItemTotal != 62.5 {
+		t.Errorf("cart line = %+v", item)
+	}
+
+	if items, _ := r.Store.FindCartByUser(ctx, other.ID); len(items) != 0 {
+		t.Errorf("another user's cart has %d lines, want 0", len(items))
+	}
+}
+
+func testCartRemoveAndClear(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+	coffee := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+	tea := mustCreateProduct(t, r, "Tea", 4, 10)
+	for _, p := range []*models.Product{coffee, tea} {
+		if err := r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: p.Variants[0].ID, Quantity: 1}); err != nil {
+			t.Fatalf("UpsertCartItem: %v", err)
+		}
+	}
+
+	if err := r.Store.DeleteCartItem(ctx, u.ID, coffee.Variants[0].ID); err != nil {
+		t.Fatalf("DeleteCartItem: %v", err)
+	}
+	items, _ := r.Store.FindCartByUser(ctx, u.ID)
+	if len(items) != 1 || items[0].ProductID != tea.ID {
+		t.Errorf("after DeleteCartItem the cart is %+v, want only tea", items)
+	}
+	// Removing something that isn't in the cart is not an error.
+	if err := r.Store.DeleteCartItem(ctx, u.ID, coffee.Variants[0].ID); err != nil {
+		t.Errorf("DeleteCartItem again: %v", err)
+	}
+
+	if err := r.Store.ClearCart(ctx, u.ID); err != nil {
+		t.Fatalf("ClearCart: %v", err)
+	}
+	if items, _ := r.Store.FindCartByUser(ctx, u.ID); len(items) != 0 {
+		t.Errorf("after ClearCart the cart has %d lines", len(items))
+	}
+}
+
+func testCartUnknownProduct(t *testing.T, r Repositories) {
+	u := mustCreateUser(t, r, "jane@example.com")
+	err := r.Store.UpsertCartItem(context.Background(), &models.CartItem{UserID: u.ID, VariantID: missingID, Quantity: 1})
+	if !errors.Is(err, repository.ErrVariantNotFound) {
+		t.Errorf("UpsertCartItem with a variant that doesn't exist = %v, want ErrVariantNotFound", err)
+	}
+}
+
+func testCartConcurrentUpserts(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	u := mustCreateUser(t, r, "jane@example.com")
+	p := mustCreateProduct(t, r, "Coffee", 12.5, 10)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- r.Store.UpsertCartItem(ctx, &models.CartItem{UserID: u.ID, VariantID: p.Variants[0].ID, Quantity: 1})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpsertCartItem: %v", err)
+		}
+	}
+
+	items, err := r.Store.FindCartByUser(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindCartByUser: %v", err)
+	}
+	if len(items) != 1 || items[0].Quantity != workers {
+		t.Errorf("cart = %+v, want one line with quantity %d", items, workers)
+	}
+}
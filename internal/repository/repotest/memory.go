@@ -0,0 +1,1183 @@
+// backend/internal/repository/repotest/memory.go
+
+// Package repotest provides in-memory implementations of the repository
+// interfaces for tests, and a contract suite (see RunContract) that every
+// implementation, in-memory or Postgres, must pass.
+package repotest
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrForeignKey is returned where Postgres would reject a write with a foreign
+// key violation, e.g. a cart item for a product that doesn't exist.
+var ErrForeignKey = errors.New("referenced row does not exist")
+
+// ErrDuplicateEmail is returned where Postgres would violate users_email_lower_idx.
+var ErrDuplicateEmail = errors.New("email already exists")
+
+type cartKey struct {
+	userID, variantID string
+}
+
+// tables holds the rows. Values are stored by value so callers can never
+// modify a row without going through a repository.
+type tables struct {
+	users      map[string]models.User
+	categories map[string]models.Category
+	products   map[string]models.Product
+	variants   map[string]models.ProductVariant
+	images     map[string]models.ProductImage
+	cart       map[cartKey]models.CartItem
+	tokens     map[string]models.UserToken
+	audit      []models.AuditEntry
+	scheduled  map[string]models.ScheduledPriceChange
+	history    []models.PriceChange
+}
+
+func (t tables) clone() tables {
+	return tables{
+		users:      maps.Clone(t.users),
+		categories: maps.Clone(t.categories),
+		products:   maps.Clone(t.products),
+		variants:   maps.Clone(t.variants),
+		images:     maps.Clone(t.images),
+		cart:       maps.Clone(t.cart),
+		tokens:     maps.Clone(t.tokens),
+		audit:      append([]models.AuditEntry(nil), t.audit...),
+		scheduled:  maps.Clone(t.scheduled),
+		history:    append([]models.PriceChange(nil), t.history...),
+	}
+}
+
+// DB is an in-memory database shared by the repositories it hands out, so a
+// user created through UserRepository can put items in a cart through
+// StoreRepository, and cascades such as deleting a user's cart behave as
+// they do in Postgres. It is safe for concurrent use.
+type DB struct {
+	mu   sync.RWMutex
+	data tables
+	last time.Time // Most recent timestamp handed out by now
+
+	txMu sync.Mutex // Serializes top-level transactions
+}
+
+// NewDB returns an empty database.
+func NewDB() *DB {
+	return &DB{data: tables{
+		users:      make(map[string]models.User),
+		categories: make(map[string]models.Category),
+		products:   make(map[string]models.Product),
+		variants:   make(map[string]models.ProductVariant),
+		images:     make(map[string]models.ProductImage),
+		cart:       make(map[cartKey]models.CartItem),
+		tokens:     make(map[string]models.UserToken),
+		scheduled:  make(map[string]models.ScheduledPriceChange),
+	}}
+}
+
+// now returns the current time at Postgres' microsecond precision, strictly
+// later than any time returned before, so "ORDER BY created_at" is stable.
+// Callers hold mu for writing.
+func (db *DB) now() time.Time {
+	t := time.Now().Truncate(time.Microsecond)
+	if !t.After(db.last) {
+		t = db.last.Add(time.Microsecond)
+	}
+	db.last = t
+	return t
+}
+
+// newID returns a random version 4 UUID, like gen_random_uuid().
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// cloneString copies the value behind p so stored rows don't alias caller memory.
+func cloneString(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	s := *p
+	return &s
+}
+
+func cloneTime(p *time.Time) *time.Time {
+	if p == nil {
+		return nil
+	}
+	t := *p
+	return &t
+}
+
+// copyUser returns u with its pointer fields copied as well.
+func copyUser(u models.User) *models.User {
+	u.Phone, u.RoleID, u.Role = cloneString(u.Phone), cloneString(u.RoleID), cloneString(u.Role)
+	u.EmailVerifiedAt = cloneTime(u.EmailVerifiedAt)
+	return &u
+}
+
+// copyProduct returns p with its options copied as well. Stored rows never
+// carry variants.
+func copyProduct(p models.Product) *models.Product {
+	p.Options = slices.Clone(p.Options)
+	return &p
+}
+
+func copyVariant(v models.ProductVariant) *models.ProductVariant {
+	v.Options = maps.Clone(v.Options)
+	v.Sale = copySale(v.Sale)
+	return &v
+}
+
+func copySale(s *models.Sale) *models.Sale {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.EndsAt = cloneTime(s.EndsAt)
+	return &c
+}
+
+func copyScheduledChange(c models.ScheduledPriceChange) *models.ScheduledPriceChange {
+	c.CreatedBy, c.AppliedAt = cloneString(c.CreatedBy), cloneTime(c.AppliedAt)
+	return &c
+}
+
+func copyPriceChange(c models.PriceChange) *models.PriceChange {
+	c.ChangedBy, c.ScheduledChangeID = cloneString(c.ChangedBy), cloneString(c.ScheduledChangeID)
+	return &c
+}
+
+// AddCategory inserts a category and returns its ID. No repository creates
+// categories yet, so tests seed them directly.
+func (db *DB) AddCategory(name, description string) string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := db.now()
+	c := models.Category{ID: newID(), Name: name, CreatedAt: now, UpdatedAt: now}
+	if description != "" {
+		c.Description.String, c.Description.Valid = description, true
+	}
+	db.data.categories[c.ID] = c
+	return c.ID
+}
+
+// AddImage inserts an image of a product, or of one of its variants if
+// variantID isn't empty, and returns its ID. No repository creates images yet,
+// so tests seed them directly.
+func (db *DB) AddImage(productID, variantID, url string, primary bool) (string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.data.products[productID]; !ok {
+		return "", ErrForeignKey
+	}
+	now := db.now()
+	img := models.ProductImage{ID: newID(), ProductID: productID, URL: url, IsPrimary: primary, CreatedAt: now, UpdatedAt: now}
+	if variantID != "" {
+		if v, ok := db.data.variants[variantID]; !ok || v.ProductID != productID {
+			return "", ErrForeignKey
+		}
+		img.VariantID.String, img.VariantID.Valid = variantID, true
+	}
+	db.data.images[img.ID] = img
+	return img.ID, nil
+}
+
+// SetRole gives a user a role, such as "admin", without the checks and
+// token revocation of AdminRepository.SetUserRole.
+func (db *DB) SetRole(userID, role string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	u, ok := db.data.users[userID]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	roleID := "role:" + role
+	u.RoleID, u.Role = &roleID, &role
+	db.data.users[userID] = u
+	return nil
+}
+
+// AuditEntries returns the audit log, oldest first.
+func (db *DB) AuditEntries() []*models.AuditEntry {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	entries := make([]*models.AuditEntry, len(db.data.audit))
+	for i := range db.data.audit {
+		e := db.data.audit[i]
+		entries[i] = &e
+	}
+	return entries
+}
+
+// UserRepository returns a UserRepository backed by db.
+func (db *DB) UserRepository() repository.UserRepository { return userRepository{db} }
+
+// UserTokenRepository returns a UserTokenRepository backed by db.
+func (db *DB) UserTokenRepository() repository.UserTokenRepository { return tokenRepository{db} }
+
+// ProductRepository returns a ProductRepository backed by db.
+func (db *DB) ProductRepository() repository.ProductRepository { return productRepository{db} }
+
+// StoreRepository returns a StoreRepository backed by db.
+func (db *DB) StoreRepository() repository.StoreRepository { return storeRepository{db} }
+
+// AdminRepository returns an AdminRepository backed by db.
+func (db *DB) AdminRepository() repository.AdminRepository { return adminRepository{db} }
+
+// LogRepository returns a LogRepository backed by db.
+func (db *DB) LogRepository() repository.LogRepository { return logRepository{db} }
+
+// PriceRepository returns a PriceRepository backed by db.
+func (db *DB) PriceRepository() repository.PriceRepository { return priceRepository{db} }
+
+// TxManager returns a TxManager for db's repositories.
+func (db *DB) TxManager() repository.TxManager { return txManager{db} }
+
+// --- Transactions ---
+
+type txKey struct{ db *DB }
+
+// txManager gives all-or-nothing semantics by snapshotting the tables and
+// restoring them if fn fails. Top-level transactions run one at a time; calls
+// made outside a transaction are not isolated from one, and a rollback also
+// undoes their writes, which is fine for tests that don't mix the two.
+type txManager struct {
+	db *DB
+}
+
+func (m txManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.WithTxOptions(ctx, repository.TxOptions{}, fn)
+}
+
+// WithTxOptions ignores opts: transactions are already serialized.
+func (m txManager) WithTxOptions(ctx context.Context, _ repository.TxOptions, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{m.db}) == nil {
+		m.db.txMu.Lock()
+		defer m.db.txMu.Unlock()
+		ctx = context.WithValue(ctx, txKey{m.db}, true)
+	}
+
+	m.db.mu.RLock()
+	snapshot := m.db.data.clone()
+	m.db.mu.RUnlock()
+
+	rollback := func() {
+		m.db.mu.Lock()
+		m.db.data = snapshot
+		m.db.mu.Unlock()
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// --- Users ---
+
+type userRepository struct {
+	db *DB
+}
+
+func (r userRepository) Create(ctx context.Context, user *models.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, u := range r.db.data.users {
+		if strings.EqualFold(u.Email, user.Email) {
+			return ErrDuplicateEmail
+		}
+	}
+
+	now := r.db.now()
+	row := models.User{
+		ID:                newID(),
+		FullName:          user.FullName,
+		Email:             user.Email,
+		PasswordHash:      user.PasswordHash,
+		PreferredLanguage: models.LanguageEN,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	r.db.data.users[row.ID] = row
+	user.ID, user.CreatedAt, user.UpdatedAt = row.ID, row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (r userRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	u, ok := r.db.data.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (r userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, u := range r.db.data.users {
+		if strings.EqualFold(u.Email, email) {
+			return copyUser(u), nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r userRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	u, ok := r.db.data.users[user.ID]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	u.FullName, u.Phone, u.PreferredLanguage = user.FullName, cloneString(user.Phone), user.PreferredLanguage
+	u.UpdatedAt = r.db.now()
+	r.db.data.users[u.ID] = u
+	user.UpdatedAt = u.UpdatedAt
+	return nil
+}
+
+func (r userRepository) UpdatePassword(ctx context.Context, id, passwordHash string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	u, ok := r.db.data.users[id]
+	if !ok {
+		return 0, repository.ErrUserNotFound
+	}
+	u.PasswordHash = passwordHash
+	u.TokenVersion++
+	u.UpdatedAt = r.db.now()
+	r.db.data.users[id] = u
+	return u.TokenVersion, nil
+}
+
+func (r userRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	u, ok := r.db.data.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	now := r.db.now()
+	if u.EmailVerifiedAt == nil {
+		u.EmailVerifiedAt = &now
+	}
+	u.UpdatedAt = now
+	r.db.data.users[id] = u
+	return nil
+}
+
+func (r userRepository) Delete(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.users[id]; !ok {
+		return repository.ErrUserNotFound
+	}
+	delete(r.db.data.users, id)
+	// ON DELETE CASCADE
+	for key := range r.db.data.cart {
+		if key.userID == id {
+			delete(r.db.data.cart, key)
+		}
+	}
+	for tokenID, t := range r.db.data.tokens {
+		if t.UserID == id {
+			delete(r.db.data.tokens, tokenID)
+		}
+	}
+	return nil
+}
+
+// --- User tokens ---
+
+type tokenRepository struct {
+	db *DB
+}
+
+func (r tokenRepository) Create(ctx context.Context, t *models.UserToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.users[t.UserID]; !ok {
+		return ErrForeignKey
+	}
+	row := models.UserToken{
+		ID:        newID(),
+		UserID:    t.UserID,
+		Purpose:   t.Purpose,
+		TokenHash: t.TokenHash,
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: r.db.now(),
+	}
+	r.db.data.tokens[row.ID] = row
+	t.ID, t.CreatedAt = row.ID, row.CreatedAt
+	return nil
+}
+
+func (r tokenRepository) Consume(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := r.db.now()
+	for id, t := range r.db.data.tokens {
+		if t.Purpose == purpose && t.TokenHash == tokenHash && t.UsedAt == nil && t.ExpiresAt.After(now) {
+			t.UsedAt = &now
+			r.db.data.tokens[id] = t
+			t.UsedAt = cloneTime(t.UsedAt)
+			return &t, nil
+		}
+	}
+	return nil, repository.ErrTokenInvalid
+}
+
+func (r tokenRepository) DeleteForUser(ctx context.Context, userID, purpose string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, t := range r.db.data.tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			delete(r.db.data.tokens, id)
+		}
+	}
+	return nil
+}
+
+func (r tokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	var deleted int64
+	for id, t := range r.db.data.tokens {
+		if t.UsedAt != nil || t.ExpiresAt.Before(now) {
+			delete(r.db.data.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// --- Products ---
+
+type productRepository struct {
+	db *DB
+}
+
+func (r productRepository) FindAll(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	all := make([]*models.Product, 0, len(r.db.data.products))
+	for _, p := range r.db.data.products {
+		all = append(all, copyProduct(p))
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+
+	if offset >= len(all) {
+		return nil, nil
+	}
+	all = all[offset:]
+	if limit < len(all) {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (r productRepository) FindByID(ctx context.Context, id string) (*models.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	p, ok := r.db.data.products[id]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	return copyProduct(p), nil
+}
+
+func (r productRepository) FindVariant(ctx context.Context, id string) (*models.ProductVariant, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	v, ok := r.db.data.variants[id]
+	if !ok {
+		return nil, repository.ErrVariantNotFound
+	}
+	return copyVariant(v), nil
+}
+
+func (r productRepository) FindVariants(ctx context.Context, productIDs []string) ([]*models.ProductVariant, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	return r.db.variantsOf(productIDs...), nil
+}
+
+// variantsOf returns the variants of the given products, ordered by product
+// and then position. Callers hold mu.
+func (db *DB) variantsOf(productIDs ...string) []*models.ProductVariant {
+	var variants []*models.ProductVariant
+	for _, v := range db.data.variants {
+		if slices.Contains(productIDs, v.ProductID) {
+			variants = append(variants, copyVariant(v))
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		a, b := variants[i], variants[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return variants
+}
+
+func (r productRepository) FindImages(ctx context.Context, productIDs []string) ([]*models.ProductImage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var images []*models.ProductImage
+	for _, img := range r.db.data.images {
+		if slices.Contains(productIDs, img.ProductID) {
+			images = append(images, &img)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if a.IsPrimary != b.IsPrimary {
+			return a.IsPrimary
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return images, nil
+}
+
+func (r productRepository) FindAllCategories(ctx context.Context) ([]*models.Category, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var categories []*models.Category
+	for _, c := range r.db.data.categories {
+		categories = append(categories, &c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+// --- Store ---
+
+type storeRepository struct {
+	db *DB
+}
+
+func (r storeRepository) UpsertCartItem(ctx context.Context, item *models.CartItem) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.users[item.UserID]; !ok {
+		return repository.ErrUserNotFound
+	}
+	if _, ok := r.db.data.variants[item.VariantID]; !ok {
+		return repository.ErrVariantNotFound
+	}
+
+	key := cartKey{item.UserID, item.VariantID}
+	now := r.db.now()
+	row, exists := r.db.data.cart[key]
+	if !exists {
+		row = models.CartItem{UserID: item.UserID, VariantID: item.VariantID, CreatedAt: now}
+	}
+	row.Quantity += item.Quantity
+	row.UpdatedAt = now
+	r.db.data.cart[key] = row
+	return nil
+}
+
+func (r storeRepository) FindCartByUser(ctx context.Context, userID string) ([]*models.CartItemDetail, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	now := time.Now()
+	var items []*models.CartItemDetail
+	for key, ci := range r.db.data.cart {
+		if key.userID != userID {
+			continue
+		}
+		v := r.db.data.variants[key.variantID]
+		price := v.EffectivePrice(now)
+		items = append(items, &models.CartItemDetail{
+			VariantID:     ci.VariantID,
+			ProductID:     v.ProductID,
+			SKU:           v.SKU,
+			Options:       maps.Clone(v.Options),
+			Quantity:      ci.Quantity,
+			ProductName:   r.db.data.products[v.ProductID].Name,
+			PricePerUnit:  price,
+			RegularPrice:  v.Price,
+			OnSale:        v.OnSale(now),
+			LineItemTotal: price * float64(ci.Quantity),
+			AddedAt:       ci.CreatedAt,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].AddedAt.After(items[j].AddedAt) })
+	return items, nil
+}
+
+func (r storeRepository) DeleteCartItem(ctx context.Context, userID, variantID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.data.cart, cartKey{userID, variantID})
+	return nil
+}
+
+func (r storeRepository) ClearCart(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for key := range r.db.data.cart {
+		if key.userID == userID {
+			delete(r.db.data.cart, key)
+		}
+	}
+	return nil
+}
+
+// --- Admin ---
+
+type adminRepository struct {
+	db *DB
+}
+
+// insertProduct stores a copy of p with generated fields set, keeping an ID
+// the caller chose. Callers hold mu.
+func (r adminRepository) insertProduct(p *models.Product) (models.Product, error) {
+	if p.CategoryID.Valid {
+		if _, ok := r.db.data.categories[p.CategoryID.String]; !ok {
+			return models.Product{}, ErrForeignKey
+		}
+	}
+	row := *p
+	row.Options, row.Variants = slices.Clone(p.Options), nil
+	if row.ID == "" {
+		row.ID = newID()
+	}
+	now := r.db.now()
+	row.CreatedAt, row.UpdatedAt = now, now
+	r.db.data.products[row.ID] = row
+	return row, nil
+}
+
+func (r adminRepository) CreateProduct(ctx context.Context, p *models.Product) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p.ID = ""
+	row, err := r.insertProduct(p)
+	if err != nil {
+		return err
+	}
+	p.ID, p.CreatedAt, p.UpdatedAt = row.ID, row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+// CreateProducts inserts all products or, like COPY, none of them.
+func (r adminRepository) CreateProducts(ctx context.Context, products []*models.Product) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	before := maps.Clone(r.db.data.products)
+	for _, p := range products {
+		row, err := r.insertProduct(p)
+		if err != nil {
+			r.db.data.products = before
+			return 0, err
+		}
+		p.ID = row.ID
+	}
+	return int64(len(products)), nil
+}
+
+// FindProductForUpdate needs no lock: transactions are already serialized.
+func (r adminRepository) FindProductForUpdate(ctx context.Context, id string) (*models.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	row, ok := r.db.data.products[id]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	p := copyProduct(row)
+	p.Variants = r.db.variantsOf(id)
+	return p, nil
+}
+
+func (r adminRepository) UpdateProduct(ctx context.Context, p *models.Product) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	row, ok := r.db.data.products[p.ID]
+	if !ok {
+		return repository.ErrProductNotFound
+	}
+	if p.CategoryID.Valid {
+		if _, ok := r.db.data.categories[p.CategoryID.String]; !ok {
+			return ErrForeignKey
+		}
+	}
+	row.CategoryID, row.Name, row.Description = p.CategoryID, p.Name, p.Description
+	row.BadgeEN, row.BadgeFI = p.BadgeEN, p.BadgeFI
+	row.UpdatedAt = r.db.now()
+	r.db.data.products[p.ID] = row
+	p.CreatedAt, p.UpdatedAt = row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (r adminRepository) DeleteProduct(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.products[id]; !ok {
+		return repository.ErrProductNotFound
+	}
+	delete(r.db.data.products, id)
+	// ON DELETE CASCADE, through the variants to the cart items
+	for variantID, v := range r.db.data.variants {
+		if v.ProductID == id {
+			r.db.deleteVariant(variantID)
+		}
+	}
+	for imageID, img := range r.db.data.images {
+		if img.ProductID == id {
+			delete(r.db.data.images, imageID)
+		}
+	}
+	return nil
+}
+
+// deleteVariant removes a variant along with the rows that cascade from it.
+// Callers hold mu.
+func (db *DB) deleteVariant(id string) {
+	delete(db.data.variants, id)
+	for key := range db.data.cart {
+		if key.variantID == id {
+			delete(db.data.cart, key)
+		}
+	}
+	for imageID, img := range db.data.images {
+		if img.VariantID.Valid && img.VariantID.String == id {
+			delete(db.data.images, imageID)
+		}
+	}
+	for changeID, c := range db.data.scheduled {
+		if c.VariantID == id {
+			delete(db.data.scheduled, changeID)
+		}
+	}
+	// The history has no foreign key to the variant and stays; only its links
+	// to the deleted scheduled changes are cleared.
+	for i, c := range db.data.history {
+		if c.ScheduledChangeID != nil {
+			if _, ok := db.data.scheduled[*c.ScheduledChangeID]; !ok {
+				db.data.history[i].ScheduledChangeID = nil
+			}
+		}
+	}
+}
+
+func (r adminRepository) AdjustProductInventory(ctx context.Context, id string, change int) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	variants := r.db.variantsOf(id)
+	switch len(variants) {
+	case 0:
+		return 0, repository.ErrProductNotFound
+	case 1:
+		return r.db.adjustVariant(variants[0].ID, change), nil
+	default:
+		return 0, repository.ErrMultipleVariants
+	}
+}
+
+// adjustVariant changes the stock of an existing variant. Callers hold mu.
+func (db *DB) adjustVariant(id string, change int) int {
+	v := db.data.variants[id]
+	v.InventoryCount += change
+	v.UpdatedAt = db.now()
+	db.data.variants[id] = v
+	return v.InventoryCount
+}
+
+// checkVariant reports the unique constraints v would violate next to the
+// stored variants other than itself. Callers hold mu.
+func (db *DB) checkVariant(v models.ProductVariant) error {
+	for _, other := range db.data.variants {
+		if other.ID == v.ID {
+			continue
+		}
+		if other.SKU == v.SKU {
+			return repository.ErrDuplicateSKU
+		}
+		if other.ProductID == v.ProductID && maps.Equal(other.Options, v.Options) {
+			return repository.ErrDuplicateVariant
+		}
+	}
+	return nil
+}
+
+// CreateVariants inserts all variants or, like a single INSERT, none of them.
+func (r adminRepository) CreateVariants(ctx context.Context, variants []*models.ProductVariant) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	before := maps.Clone(r.db.data.variants)
+	rows := make([]models.ProductVariant, len(variants))
+	for i, v := range variants {
+		if _, ok := r.db.data.products[v.ProductID]; !ok {
+			r.db.data.variants = before
+			return ErrForeignKey
+		}
+		row := *v
+		row.Options, row.Sale = maps.Clone(v.Options), nil
+		if row.Options == nil {
+			row.Options = map[string]string{}
+		}
+		now := r.db.now()
+		row.ID, row.CreatedAt, row.UpdatedAt = newID(), now, now
+		if err := r.db.checkVariant(row); err != nil {
+			r.db.data.variants = before
+			return err
+		}
+		r.db.data.variants[row.ID] = row
+		rows[i] = row
+	}
+	for i, v := range variants {
+		v.ID, v.CreatedAt, v.UpdatedAt = rows[i].ID, rows[i].CreatedAt, rows[i].UpdatedAt
+	}
+	return nil
+}
+
+func (r adminRepository) FindVariantForUpdate(ctx context.Context, id string) (*models.ProductVariant, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	v, ok := r.db.data.variants[id]
+	if !ok {
+		return nil, repository.ErrVariantNotFound
+	}
+	return copyVariant(v), nil
+}
+
+func (r adminRepository) UpdateVariant(ctx context.Context, v *models.ProductVariant) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	row, ok := r.db.data.variants[v.ID]
+	if !ok {
+		return repository.ErrVariantNotFound
+	}
+	row.SKU, row.Options, row.Position = v.SKU, maps.Clone(v.Options), v.Position
+	if row.Options == nil {
+		row.Options = map[string]string{}
+	}
+	if err := r.db.checkVariant(row); err != nil {
+		return err
+	}
+	row.UpdatedAt = r.db.now()
+	r.db.data.variants[v.ID] = row
+	*v = *copyVariant(row)
+	return nil
+}
+
+func (r adminRepository) DeleteVariant(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.variants[id]; !ok {
+		return repository.ErrVariantNotFound
+	}
+	r.db.deleteVariant(id)
+	return nil
+}
+
+func (r adminRepository) AdjustVariantInventory(ctx context.Context, id string, change int) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.variants[id]; !ok {
+		return 0, repository.ErrVariantNotFound
+	}
+	return r.db.adjustVariant(id, change), nil
+}
+
+// roles are the roles the migrations create.
+var roles = []string{"customer", "admin"}
+
+func (r adminRepository) SetUserRole(ctx context.Context, userID, role string) (*string, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	u, ok := r.db.data.users[userID]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	if !slices.Contains(roles, role) {
+		return nil, repository.ErrRoleNotFound
+	}
+	previous := cloneString(u.Role)
+	if previous == nil || *previous != role {
+		u.TokenVersion++
+	}
+	roleID := "role:" + role
+	u.RoleID, u.Role = &roleID, &role
+	u.UpdatedAt = r.db.now()
+	r.db.data.users[userID] = u
+	return previous, nil
+}
+
+// --- Audit log ---
+
+type logRepository struct {
+	db *DB
+}
+
+func (r logRepository) Append(ctx context.Context, e *models.AuditEntry) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	row := *e
+	row.ID, row.CreatedAt = newID(), r.db.now()
+	row.ActorID = cloneString(e.ActorID)
+	row.Details = append([]byte(nil), e.Details...)
+	row.Changes = append([]byte(nil), e.Changes...)
+	r.db.data.audit = append(r.db.data.audit, row)
+	e.ID, e.CreatedAt = row.ID, row.CreatedAt
+	return nil
+}
+
+func (r logRepository) Find(ctx context.Context, f repository.AuditFilter) ([]*models.AuditEntry, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var entries []*models.AuditEntry
+	// Entries are appended with increasing timestamps, so walking backwards is newest first.
+	for i := len(r.db.data.audit) - 1; i >= 0; i-- {
+		e := r.db.data.audit[i]
+		switch {
+		case f.ActorID != "" && (e.ActorID == nil || *e.ActorID != f.ActorID),
+			f.EntityType != "" && e.EntityType != f.EntityType,
+			f.EntityID != "" && e.EntityID != f.EntityID,
+			!f.From.IsZero() && e.CreatedAt.Before(f.From),
+			!f.To.IsZero() && !e.CreatedAt.Before(f.To):
+			continue
+		}
+		e.ActorID = cloneString(e.ActorID)
+		entries = append(entries, &e)
+	}
+
+	if f.Offset >= len(entries) {
+		return nil, nil
+	}
+	entries = entries[f.Offset:]
+	if f.Limit < len(entries) {
+		entries = entries[:f.Limit]
+	}
+	return entries, nil
+}
+
+// --- Prices ---
+
+type priceRepository struct {
+	db *DB
+}
+
+func (r priceRepository) SetPrice(ctx context.Context, variantID string, price float64) (float64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	v, ok := r.db.data.variants[variantID]
+	if !ok {
+		return 0, repository.ErrVariantNotFound
+	}
+	previous := v.Price
+	v.Price, v.UpdatedAt = price, r.db.now()
+	r.db.data.variants[variantID] = v
+	return previous, nil
+}
+
+func (r priceRepository) SetSale(ctx context.Context, variantID string, sale *models.Sale) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	v, ok := r.db.data.variants[variantID]
+	if !ok {
+		return repository.ErrVariantNotFound
+	}
+	v.Sale, v.UpdatedAt = copySale(sale), r.db.now()
+	r.db.data.variants[variantID] = v
+	return nil
+}
+
+func (r priceRepository) SalesChangedBetween(ctx context.Context, from, to time.Time) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	between := func(t time.Time) bool { return t.After(from) && !t.After(to) }
+	for _, v := range r.db.data.variants {
+		if v.Sale != nil && (between(v.Sale.StartsAt) || v.Sale.EndsAt != nil && between(*v.Sale.EndsAt)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r priceRepository) SchedulePriceChange(ctx context.Context, c *models.ScheduledPriceChange) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.data.variants[c.VariantID]; !ok {
+		return repository.ErrVariantNotFound
+	}
+	row := *copyScheduledChange(*c)
+	row.ID, row.CreatedAt, row.AppliedAt = newID(), r.db.now(), nil
+	r.db.data.scheduled[row.ID] = row
+	c.ID, c.CreatedAt = row.ID, row.CreatedAt
+	return nil
+}
+
+// pendingChanges returns the pending changes matching keep, oldest
+// effective_at first. Callers hold mu.
+func (db *DB) pendingChanges(keep func(models.ScheduledPriceChange) bool) []*models.ScheduledPriceChange {
+	var changes []*models.ScheduledPriceChange
+	for _, c := range db.data.scheduled {
+		if c.AppliedAt == nil && keep(c) {
+			changes = append(changes, copyScheduledChange(c))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if !a.EffectiveAt.Equal(b.EffectiveAt) {
+			return a.EffectiveAt.Before(b.EffectiveAt)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return changes
+}
+
+func (r priceRepository) FindPendingChanges(ctx context.Context, variantID string) ([]*models.ScheduledPriceChange, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	return r.db.pendingChanges(func(c models.ScheduledPriceChange) bool { return c.VariantID == variantID }), nil
+}
+
+func (r priceRepository) CancelPriceChange(ctx context.Context, id string) (*models.ScheduledPriceChange, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.data.scheduled[id]
+	if !ok || c.AppliedAt != nil {
+		return nil, repository.ErrPriceChangeNotFound
+	}
+	delete(r.db.data.scheduled, id)
+	return copyScheduledChange(c), nil
+}
+
+// FindDueChanges needs no lock: transactions are already serialized.
+func (r priceRepository) FindDueChanges(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledPriceChange, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	changes := r.db.pendingChanges(func(c models.ScheduledPriceChange) bool { return !c.EffectiveAt.After(now) })
+	if limit < len(changes) {
+		changes = changes[:limit]
+	}
+	return changes, nil
+}
+
+func (r priceRepository) MarkApplied(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.data.scheduled[id]
+	if !ok || c.AppliedAt != nil {
+		return repository.ErrPriceChangeNotFound
+	}
+	now := r.db.now()
+	c.AppliedAt = &now
+	r.db.data.scheduled[id] = c
+	return nil
+}
+
+func (r priceRepository) AppendHistory(ctx context.Context, c *models.PriceChange) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	row := *copyPriceChange(*c)
+	row.ID, row.ChangedAt = newID(), r.db.now()
+	r.db.data.history = append(r.db.data.history, row)
+	c.ID, c.ChangedAt = row.ID, row.ChangedAt
+	return nil
+}
+
+func (r priceRepository) FindHistory(ctx context.Context, variantID string, limit, offset int) ([]*models.PriceChange, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var changes []*models.PriceChange
+	// Changes are appended with increasing timestamps, so walking backwards is newest first.
+	for i := len(r.db.data.history) - 1; i >= 0; i-- {
+		if c := r.db.data.history[i]; c.VariantID == variantID {
+			changes = append(changes, copyPriceChange(c))
+		}
+	}
+
+	if offset >= len(changes) {
+		return nil, nil
+	}
+	changes = changes[offset:]
+	if limit < len(changes) {
+		changes = changes[:limit]
+	}
+	return changes, nil
+}
@@ -0,0 +1,73 @@
+// backend/internal/repository/repotest/memory_test.go
+package repotest
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryContract(t *testing.T) {
+	RunContract(t, func(t *testing.T) Repositories {
+		db := NewDB()
+		return Repositories{
+			Users:    db.UserRepository(),
+			Products: db.ProductRepository(),
+			Store:    db.StoreRepository(),
+			Admin:    db.AdminRepository(),
+			Prices:   db.PriceRepository(),
+			AddCategory: func(t *testing.T, name string) string {
+				return db.AddCategory(name, "")
+			},
+		}
+	})
+}
+
+func TestMemoryTxRollback(t *testing.T) {
+	db := NewDB()
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	err := db.TxManager().WithTx(ctx, func(ctx context.Context) error {
+		kept := &models.Product{Name: "Kept"}
+		if err := db.AdminRepository().CreateProduct(ctx, kept); err != nil {
+			return err
+		}
+		if err := db.AdminRepository().CreateVariants(ctx, []*models.ProductVariant{{ProductID: kept.ID, SKU: "KEPT", Price: 1}}); err != nil {
+			return err
+		}
+		// A failed nested transaction only undoes its own writes.
+		nested := db.TxManager().WithTx(ctx, func(ctx context.Context) error {
+			if err := db.AdminRepository().CreateProduct(ctx, &models.Product{Name: "Undone"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(nested, errAbort) {
+			t.Errorf("nested WithTx = %v, want errAbort", nested)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	products, _ := db.ProductRepository().FindAll(ctx, 10, 0)
+	if len(products) != 1 || products[0].Name != "Kept" {
+		t.Fatalf("products = %v, want only Kept", products)
+	}
+
+	err = db.TxManager().WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.AdminRepository().AdjustProductInventory(ctx, products[0].ID, 5); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx = %v, want errAbort", err)
+	}
+	if v, _ := db.ProductRepository().FindVariants(ctx, []string{products[0].ID}); len(v) != 1 || v[0].InventoryCount != 0 {
+		t.Errorf("variants = %+v after rollback, want one with no stock", v)
+	}
+}
@@ -4,77 +4,114 @@ package repository
 import (
 	"backend/internal/models"
 	"context"
-	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // StoreRepository abstracts DB operations for cart, orders, etc.
 type StoreRepository interface {
 	// Cart methods
+
+	// UpsertCartItem adds item.Quantity to the user's cart, returning
+	// ErrVariantNotFound or ErrUserNotFound if either doesn't exist.
 	UpsertCartItem(ctx context.Context, item *models.CartItem) error
 	FindCartByUser(ctx context.Context, userID string) ([]*models.CartItemDetail, error)
-	DeleteCartItem(ctx context.Context, userID, productID string) error
+	DeleteCartItem(ctx context.Context, userID, variantID string) error
 	ClearCart(ctx context.Context, userID string) error
 }
 
 type postgresStoreRepository struct {
-	db *sql.DB
+	db *instrumentedDB
 }
 
-func NewPostgresStoreRepository(db *sql.DB) StoreRepository {
-	return &postgresStoreRepository{db: db}
+func NewPostgresStoreRepository(pool *pgxpool.Pool) StoreRepository {
+	return &postgresStoreRepository{db: instrument(pool, "store")}
 }
 
 func (r *postgresStoreRepository) UpsertCartItem(ctx context.Context, item *models.CartItem) error {
 	query := `
-		INSERT INTO cart_items (user_id, product_id, quantity)
+		INSERT INTO cart_items (user_id, variant_id, quantity)
 		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, product_id) DO UPDATE
+		ON CONFLICT (user_id, variant_id) DO UPDATE
 		SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
 	`
-	_, err := r.db.ExecContext(ctx, query, item.UserID, item.ProductID, item.Quantity)
+	_, err := r.db.Exec(ctx, query, item.UserID, item.VariantID, item.Quantity)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "cart_items_user_id_fkey" {
+			return ErrUserNotFound
+		}
+		return ErrVariantNotFound
+	}
 	return err
 }
 
 func (r *postgresStoreRepository) FindCartByUser(ctx context.Context, userID string) ([]*models.CartItemDetail, error) {
 	query := `
 		SELECT
-			ci.product_id,
+			ci.variant_id,
+			v.product_id,
+			v.sku,
+			v.options,
 			ci.quantity,
 			p.name,
-			p.price,
+			v.price,
+			v.sale_price,
+			v.sale_starts_at,
+			v.sale_ends_at,
 			ci.created_at
 		FROM cart_items ci
-		JOIN products p ON ci.product_id = p.id
+		JOIN product_variants v ON ci.variant_id = v.id
+		JOIN products p ON v.product_id = p.id
 		WHERE ci.user_id = $1
 		ORDER BY ci.created_at DESC
 	`
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	var items []*models.CartItemDetail
 	for rows.Next() {
 		item := new(models.CartItemDetail)
-		if err := rows.Scan(&item.ProductID, &item.Quantity, &item.ProductName, &item.PricePerUnit, &item.AddedAt); err != nil {
+		v := new(models.ProductVariant)
+		var salePrice *float64
+		var saleStartsAt, saleEndsAt *time.Time
+		if err := rows.Scan(
+			&item.VariantID, &item.ProductID, &item.SKU, &item.Options, &item.Quantity, &item.ProductName,
+			&v.Price, &salePrice, &saleStartsAt, &saleEndsAt, &item.AddedAt,
+		); err != nil {
 			return nil, err
 		}
-		item.LineItemTotal = item.PricePerUnit * float64(item.Quantity)
+		v.Sale = saleFrom(salePrice, saleStartsAt, saleEndsAt)
+		setCartPrices(item, v, now)
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items, rows.Err()
+}
+
+// setCartPrices prices a cart line at t from the variant it holds.
+func setCartPrices(item *models.CartItemDetail, v *models.ProductVariant, t time.Time) {
+	item.RegularPrice = v.Price
+	item.OnSale = v.OnSale(t)
+	item.PricePerUnit = v.EffectivePrice(t)
+	item.LineItemTotal = item.PricePerUnit * float64(item.Quantity)
 }
 
-func (r *postgresStoreRepository) DeleteCartItem(ctx context.Context, userID, productID string) error {
-	query := `DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`
-	_, err := r.db.ExecContext(ctx, query, userID, productID)
+func (r *postgresStoreRepository) DeleteCartItem(ctx context.Context, userID, variantID string) error {
+	query := `DELETE FROM cart_items WHERE user_id = $1 AND variant_id = $2`
+	_, err := r.db.Exec(ctx, query, userID, variantID)
 	return err
 }
 
 func (r *postgresStoreRepository) ClearCart(ctx context.Context, userID string) error {
 	query := `DELETE FROM cart_items WHERE user_id = $1`
-	_, err := r.db.ExecContext(ctx, query, userID)
+	_, err := r.db.Exec(ctx, query, userID)
 	return err
 }
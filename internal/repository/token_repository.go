@@ -0,0 +1,77 @@
+// backend/internal/repository/token_repository.go
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTokenInvalid is returned when a token does not exist, was already used or has expired.
+var ErrTokenInvalid = errors.New("token is invalid or has expired")
+
+// UserTokenRepository stores single-use tokens for email verification and password reset.
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	// Consume atomically marks an unused, unexpired token as used and returns it.
+	Consume(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	// DeleteForUser removes all outstanding tokens of a purpose, so only the newest one works.
+	DeleteForUser(ctx context.Context, userID, purpose string) error
+	// DeleteExpired removes used and expired tokens and returns how many were deleted.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type postgresUserTokenRepository struct {
+	db *instrumentedDB
+}
+
+func NewPostgresUserTokenRepository(pool *pgxpool.Pool) UserTokenRepository {
+	return &postgresUserTokenRepository{db: instrument(pool, "user_token")}
+}
+
+func (r *postgresUserTokenRepository) Create(ctx context.Context, t *models.UserToken) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query, t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+}
+
+func (r *postgresUserTokenRepository) Consume(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+	t := new(models.UserToken)
+	err := r.db.QueryRow(ctx, query, purpose, tokenHash).Scan(
+		&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *postgresUserTokenRepository) DeleteForUser(ctx context.Context, userID, purpose string) error {
+	query := `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`
+	_, err := r.db.Exec(ctx, query, userID, purpose)
+	return err
+}
+
+func (r *postgresUserTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM user_tokens WHERE used_at IS NOT NULL OR expires_at < NOW()`
+	res, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,223 @@
+// backend/internal/repository/tx.go
+package repository
+
+import (
+	"backend/pkg/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrTxIsolation is returned when a nested WithTx asks for a stricter
+// isolation level than the transaction it joins.
+var ErrTxIsolation = errors.New("nested transaction cannot raise the isolation level")
+
+// TxOptions configures a transaction started by TxManager.
+type TxOptions struct {
+	// Isolation defaults to the database default (READ COMMITTED in Postgres).
+	Isolation pgx.TxIsoLevel
+	ReadOnly  bool
+	// MaxRetries is how often the whole transaction is retried after a
+	// serialization failure or deadlock. Zero means DefaultTxRetries; a
+	// negative value disables retrying.
+	MaxRetries int
+}
+
+// DefaultTxRetries is the retry budget used when TxOptions.MaxRetries is zero.
+const DefaultTxRetries = 3
+
+// TxManager runs a function as a unit of work. Every repository built on the
+// same pool picks the transaction up from the context passed to fn, so
+// services compose repository calls atomically without knowing about pgx.Tx:
+//
+//	err := txm.WithTx(ctx, func(ctx context.Context) error {
+//		if _, err := adminRepo.AdjustProductInventory(ctx, id, change); err != nil {
+//			return err
+//		}
+//		return logRepo.Append(ctx, entry)
+//	})
+//
+// The transaction commits when fn returns nil and rolls back when it returns an
+// error or panics. A WithTx inside fn doesn't start a new transaction; it runs
+// under a savepoint, so its failure only undoes its own work if the caller
+// handles the error. Because fn may run more than once when retried, it must
+// not have side effects outside the database, such as sending email.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	WithTxOptions(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error
+}
+
+var txRetriesTotal = metrics.NewCounterVec(
+	"db_tx_retries_total",
+	"Transactions retried after a serialization failure or deadlock.",
+)
+
+func init() {
+	metrics.MustRegister(txRetriesTotal)
+}
+
+type txKey struct{}
+
+// txState is the transaction carried in a context. Like the pgx.Tx itself it
+// must only be used by one goroutine at a time.
+type txState struct {
+	pool      *pgxpool.Pool
+	tx        pgx.Tx
+	isolation pgx.TxIsoLevel
+}
+
+// txFrom returns the transaction in ctx if it was started on pool.
+func txFrom(ctx context.Context, pool *pgxpool.Pool) (*txState, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok || state.pool != pool {
+		return nil, false
+	}
+	return state, true
+}
+
+// --- Postgres ---
+
+type postgresTxManager struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresTxManager(pool *pgxpool.Pool) TxManager {
+	return &postgresTxManager{pool: pool}
+}
+
+func (m *postgresTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+func (m *postgresTxManager) WithTxOptions(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if state, ok := txFrom(ctx, m.pool); ok {
+		if isolationRank(opts.Isolation) > isolationRank(state.isolation) {
+			return ErrTxIsolation
+		}
+		return m.savepoint(ctx, state, fn)
+	}
+
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = DefaultTxRetries
+	}
+
+	ctx, span := tracer.Start(ctx, "transaction")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.transaction.isolation", string(opts.Isolation)))
+
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, opts, fn)
+		if err == nil || !isRetryable(err) || attempt >= retries {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+
+		txRetriesTotal.WithLabelValues().Inc()
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1)))
+		select {
+		case <-time.After(retryBackoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// run executes one attempt of a top-level transaction.
+func (m *postgresTxManager) run(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	accessMode := pgx.ReadWrite
+	if opts.ReadOnly {
+		accessMode = pgx.ReadOnly
+	}
+	tx, err := m.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: opts.Isolation, AccessMode: accessMode})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	if err := runIn(ctx, &txState{pool: m.pool, tx: tx, isolation: opts.Isolation}, fn); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// savepoint runs a nested unit of work. pgx implements Begin on a transaction
+// with SAVEPOINT, and Commit and Rollback with RELEASE and ROLLBACK TO.
+func (m *postgresTxManager) savepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	nested, err := state.tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("create savepoint: %w", err)
+	}
+	if err := runIn(ctx, &txState{pool: m.pool, tx: nested, isolation: state.isolation}, fn); err != nil {
+		return err
+	}
+	if err := nested.Commit(ctx); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// runIn calls fn with state in its context and rolls state.tx back if fn
+// fails or panics.
+func runIn(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	defer func() {
+		if p := recover(); p != nil {
+			state.tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		// A cancelled ctx must not stop the rollback.
+		if rbErr := state.tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rbErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// isolationRank orders isolation levels by strictness. Postgres runs the
+// default level, and READ UNCOMMITTED, as READ COMMITTED.
+func isolationRank(level pgx.TxIsoLevel) int {
+	switch level {
+	case pgx.RepeatableRead:
+		return 2
+	case pgx.Serializable:
+		return 3
+	}
+	return 1
+}
+
+// isRetryable reports whether err means the transaction lost a race and would
+// likely succeed if run again: a serialization failure or a deadlock.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// retryBackoff is an exponential delay with full jitter: up to 10ms, 20ms,
+// 40ms, ... capped at 500ms, so competing transactions spread out.
+func retryBackoff(attempt int) time.Duration {
+	ceiling := 10 * time.Millisecond << attempt
+	if ceiling > 500*time.Millisecond || ceiling <= 0 {
+		ceiling = 500 * time.Millisecond
+	}
+	return rand.N(ceiling) + time.Millisecond
+}
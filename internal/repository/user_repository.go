@@ -3,27 +3,38 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 
 	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrUserNotFound is returned when no user matches the lookup.
+var ErrUserNotFound = errors.New("user not found")
+
 // UserRepository is an interface that abstracts database operations for users.
-// This allows us to easily mock it for testing the service layer.
+// Package repotest has an in-memory implementation for service tests.
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByID(ctx context.Context, id string) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateProfile(ctx context.Context, user *models.User) error
+	// UpdatePassword stores a new hash and bumps the user's token version,
+	// returning the new version.
+	UpdatePassword(ctx context.Context, id, passwordHash string) (int, error)
+	MarkEmailVerified(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
 }
 
 // postgresUserRepository is the PostgreSQL implementation of the UserRepository.
 type postgresUserRepository struct {
-	db *sql.DB
+	db *instrumentedDB
 }
 
-func NewPostgresUserRepository(db *sql.DB) UserRepository {
-	return &postgresUserRepository{db: db}
+func NewPostgresUserRepository(pool *pgxpool.Pool) UserRepository {
+	return &postgresUserRepository{db: instrument(pool, "user")}
 }
 
 func (r *postgresUserRepository) Create(ctx context.Context, user *models.User) error {
@@ -32,26 +43,96 @@ func (r *postgresUserRepository) Create(ctx context.Context, user *models.User)
 		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at
 	`
-	err := r.db.QueryRowContext(ctx, query, user.FullName, user.Email, user.PasswordHash).Scan(
+	err := r.db.QueryRow(ctx, query, user.FullName, user.Email, user.PasswordHash).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt,
 	)
 	return err
 }
 
-func (r *postgresUserRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
+// selectUserQuery is shared by the single-user lookups; callers append a WHERE clause.
+const selectUserQuery = `
+	SELECT u.id, u.full_name, u.email, u.password_hash, u.phone, u.role_id, r.name,
+		u.preferred_language, u.token_version, u.email_verified_at, u.created_at, u.updated_at
+	FROM users u
+	LEFT JOIN roles r ON u.role_id = r.id
+`
+
+func (r *postgresUserRepository) findOne(ctx context.Context, where string, arg any) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, full_name, email, password_hash, created_at FROM users WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.FullName, &user.Email, &user.PasswordHash, &user.CreatedAt,
+	err := r.db.QueryRow(ctx, selectUserQuery+where, arg).Scan(
+		&user.ID, &user.FullName, &user.Email, &user.PasswordHash, &user.Phone, &user.RoleID, &user.Role,
+		&user.PreferredLanguage, &user.TokenVersion, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
 	}
-	return user, err
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *postgresUserRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
+	return r.findOne(ctx, "WHERE u.id = $1", id)
 }
 
 func (r *postgresUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	// Implementation would be very similar to FindByID
-	// ...
-	return nil, nil // Placeholder
+	return r.findOne(ctx, "WHERE lower(u.email) = lower($1)", email)
+}
+
+func (r *postgresUserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users
+		SET full_name = $2, phone = $3, preferred_language = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, user.ID, user.FullName, user.Phone, user.PreferredLanguage).Scan(&user.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func (r *postgresUserRepository) UpdatePassword(ctx context.Context, id, passwordHash string) (int, error) {
+	query := `
+		UPDATE users
+		SET password_hash = $2, token_version = token_version + 1, updated_at = NOW()
+		WHERE id = $1
+		RETURNING token_version
+	`
+	var tokenVersion int
+	err := r.db.QueryRow(ctx, query, id, passwordHash).Scan(&tokenVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	return tokenVersion, err
+}
+
+func (r *postgresUserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+	`
+	res, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *postgresUserRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM users WHERE id = $1`
+	res, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
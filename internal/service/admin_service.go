@@ -6,45 +6,597 @@ import (
 	"backend/internal/repository"
 	"context"
 	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// DTO for creating a product
+// ErrLastVariant is returned when deleting a product's only variant, which
+// would leave nothing to buy. Delete the product instead.
+var ErrLastVariant = errors.New("a product must keep at least one variant")
+
+// DTO for creating a product. A product with options lists its variants; one
+// without gets a single variant from SKU, Price and InventoryCount.
 type CreateProductRequest struct {
-	CategoryID     *string `json:"category_id"`
-	Name           string  `json:"name"`
-	Description    *string `json:"description"`
-	Price          float64 `json:"price"`
-	InventoryCount int     `json:"inventory_count"`
+	CategoryID  *string `json:"category_id" validate:"uuid"`
+	Name        string  `json:"name" validate:"required,max=200"`
+	Description *string `json:"description" validate:"max=5000"`
+	// BadgeEN and BadgeFI label the product in listings, e.g. "New".
+	BadgeEN *string `json:"badge_en" validate:"max=30"`
+	BadgeFI *string `json:"badge_fi" validate:"max=30"`
+	// Options are the axes the variants differ along, e.g. ["size"].
+	Options  []string         `json:"options" validate:"max=3"`
+	Variants []VariantRequest `json:"variants" validate:"max=100"`
+	// SKU defaults to the product's ID.
+	SKU            string  `json:"sku" validate:"max=64"`
+	Price          float64 `json:"price" validate:"min=0"`
+	InventoryCount int     `json:"inventory_count" validate:"min=0"`
+}
+
+// VariantRequest is one variant of a new product, or a variant added later.
+type VariantRequest struct {
+	SKU string `json:"sku" validate:"required,max=64"`
+	// Options has a value for each of the product's options.
+	Options        map[string]string `json:"options"`
+	Price          float64           `json:"price" validate:"min=0"`
+	InventoryCount int               `json:"inventory_count" validate:"min=0"`
+}
+
+// UpdateProductRequest replaces a product's descriptive fields. Options are
+// fixed once the product has variants; prices and stock belong to those.
+type UpdateProductRequest struct {
+	CategoryID  *string `json:"category_id" validate:"uuid"`
+	Name        string  `json:"name" validate:"required,max=200"`
+	Description *string `json:"description" validate:"max=5000"`
+	BadgeEN     *string `json:"badge_en" validate:"max=30"`
+	BadgeFI     *string `json:"badge_fi" validate:"max=30"`
+}
+
+// UpdateVariantRequest replaces a variant's fields, except its stock, which
+// only changes through inventory adjustments, and its price, which changes
+// through PricingService so that the price history stays complete.
+type UpdateVariantRequest struct {
+	SKU      string            `json:"sku" validate:"required,max=64"`
+	Options  map[string]string `json:"options"`
+	Position int               `json:"position" validate:"min=0"`
+}
+
+// BulkCreateProductsRequest imports many products at once, e.g. from a supplier feed.
+type BulkCreateProductsRequest struct {
+	Products []CreateProductRequest `json:"products" validate:"required,max=1000"`
 }
 
 // DTO for adjusting inventory
 type AdjustInventoryRequest struct {
-	Change int `json:"change"` // e.g., +10 or -5
+	Change int `json:"change" validate:"nonzero"` // e.g., +10 or -5
+}
+
+// ChangeRoleRequest assigns a user a role, e.g. "admin" or "customer".
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// AuditLogQuery filters the audit log. Empty fields match everything; From
+// (inclusive) and To (exclusive) are RFC 3339 timestamps.
+type AuditLogQuery struct {
+	ActorID    string
+	EntityType string
+	EntityID   string
+	From, To   string
+	Page       int
+	Limit      int
 }
 
 type AdminService struct {
 	adminRepo repository.AdminRepository
+	logRepo   repository.LogRepository
+	txm       repository.TxManager
+	catalog   *CatalogCache
 	// May need other repos to validate data, e.g., does category_id exist?
 	// productRepo repository.ProductRepository
 }
 
-func NewAdminService(ar repository.AdminRepository) *AdminService {
-	return &AdminService{adminRepo: ar}
+// NewAdminService returns an AdminService. Product writes invalidate catalog,
+// which may be nil.
+func NewAdminService(ar repository.AdminRepository, lr repository.LogRepository, txm repository.TxManager, catalog *CatalogCache) *AdminService {
+	return &AdminService{adminRepo: ar, logRepo: lr, txm: txm, catalog: catalog}
 }
 
-func (s *AdminService) CreateProduct(ctx context.Context, req CreateProductRequest) (*models.Product, error) {
-	// Business validation
-	if req.Name == "" {
-		return nil, errors.New("product name cannot be empty")
+// CreateProduct adds a product. The product and its audit entry are written
+// in one transaction. actorID is the admin performing it.
+func (s *AdminService) CreateProduct(ctx context.Context, req CreateProductRequest, actorID string, client ClientInfo) (*models.Product, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.CreateProduct")
+	defer span.End()
+
+	if err := validateProduct(req); err != nil {
+		return nil, err
 	}
-	if req.Price < 0 {
-		return nil, errors.New("product price cannot be negative")
+
+	product := req.toProduct()
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.adminRepo.CreateProduct(ctx, product); err != nil {
+			return err
+		}
+		product.Variants = req.toVariants(product.ID)
+		if err := s.adminRepo.CreateVariants(ctx, product.Variants); err != nil {
+			return variantConflict(err)
+		}
+		entry := newAuditEntry(&actorID, "product.create", "product", product.ID, client, nil)
+		entry.Changes = auditChanges(nil, snapshotProduct(product))
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.catalog.Invalidate(ctx)
 
-	product := &models.Product{
-		Name:           req.Name,
+	return product, nil
+}
+
+// BulkCreateProducts adds all products in req with a single COPY and returns
+// how many were created. Either every product is created or none is; one audit
+// entry records the import.
+func (s *AdminService) BulkCreateProducts(ctx context.Context, req BulkCreateProductsRequest, actorID string, client ClientInfo) (int64, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.BulkCreateProducts")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return 0, err
+	}
+	verr := &ValidationError{}
+	for i, p := range req.Products {
+		checkVariants(p, "products["+strconv.Itoa(i)+"].", verr)
+	}
+	if err := verr.errOrNil(); err != nil {
+		return 0, err
+	}
+
+	products := make([]*models.Product, len(req.Products))
+	for i := range req.Products {
+		products[i] = req.Products[i].toProduct()
+	}
+
+	var created int64
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.adminRepo.CreateProducts(ctx, products)
+		if err != nil {
+			return err
+		}
+		var variants []*models.ProductVariant
+		for i, p := range products {
+			variants = append(variants, req.Products[i].toVariants(p.ID)...)
+		}
+		if err := s.adminRepo.CreateVariants(ctx, variants); err != nil {
+			return variantConflict(err)
+		}
+		return s.logRepo.Append(ctx, newAuditEntry(&actorID, "product.bulk_create", "product", "", client, map[string]interface{}{
+			"count": created,
+		}))
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.catalog.Invalidate(ctx)
+	return created, nil
+}
+
+// AdjustInventory changes the stock of a product's only variant by req.Change
+// and returns the new count, or repository.ErrMultipleVariants if the product
+// has several; see AdjustVariantInventory. Like CreateProduct, the change is
+// only kept if it could be audited.
+func (s *AdminService) AdjustInventory(ctx context.Context, productID string, req AdjustInventoryRequest, actorID string, client ClientInfo) (int, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.AdjustInventory")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return 0, err
+	}
+	change := req.Change
+	// Future enhancement: check that inventory doesn't go below zero if change is negative.
+	var newInventory int
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		newInventory, err = s.adminRepo.AdjustProductInventory(ctx, productID, change)
+		if err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "product.inventory_adjust", "product", productID, client, map[string]interface{}{
+			"change": change,
+		})
+		entry.Changes = auditChanges(map[string]int{"inventory_count": newInventory - change}, map[string]int{"inventory_count": newInventory})
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.catalog.Invalidate(ctx)
+
+	direction := "increase"
+	if change < 0 {
+		direction = "decrease"
+	}
+	inventoryAdjustmentsTotal.WithLabelValues(direction).Inc()
+	return newInventory, nil
+}
+
+// UpdateProduct replaces the editable fields of a product. The audit entry
+// records the fields that changed, before and after.
+func (s *AdminService) UpdateProduct(ctx context.Context, productID string, req UpdateProductRequest, actorID string, client ClientInfo) (*models.Product, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.UpdateProduct")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	product := CreateProductRequest{
+		CategoryID: req.CategoryID, Name: req.Name, Description: req.Description, BadgeEN: req.BadgeEN, BadgeFI: req.BadgeFI,
+	}.toProduct()
+	product.ID = productID
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.adminRepo.FindProductForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if err := s.adminRepo.UpdateProduct(ctx, product); err != nil {
+			return err
+		}
+		product.Options, product.Variants = before.Options, before.Variants
+		entry := newAuditEntry(&actorID, "product.update", "product", productID, client, nil)
+		entry.Changes = auditChanges(snapshotProduct(before), snapshotProduct(product))
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.catalog.Invalidate(ctx)
+
+	return product, nil
+}
+
+// DeleteProduct removes a product with its variants, and with those every cart
+// line holding one. The audit entry keeps the deleted product's fields.
+func (s *AdminService) DeleteProduct(ctx context.Context, productID, actorID string, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "AdminService.DeleteProduct")
+	defer span.End()
+
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.adminRepo.FindProductForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if err := s.adminRepo.DeleteProduct(ctx, productID); err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "product.delete", "product", productID, client, nil)
+		entry.Changes = auditChanges(snapshotProduct(before), nil)
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return err
+	}
+	s.catalog.Invalidate(ctx)
+	return nil
+}
+
+// CreateVariant adds a variant to a product, after the existing ones.
+func (s *AdminService) CreateVariant(ctx context.Context, productID string, req VariantRequest, actorID string, client ClientInfo) (*models.ProductVariant, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.CreateVariant")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	var variant *models.ProductVariant
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		product, err := s.adminRepo.FindProductForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		verr := &ValidationError{}
+		checkOptions(product.Options, req.Options, "options", verr)
+		if err := verr.errOrNil(); err != nil {
+			return err
+		}
+
+		variant = req.toVariant(productID, len(product.Variants))
+		if err := s.adminRepo.CreateVariants(ctx, []*models.ProductVariant{variant}); err != nil {
+			return variantConflict(err)
+		}
+		entry := newAuditEntry(&actorID, "variant.create", "variant", variant.ID, client, map[string]interface{}{
+			"product_id": productID,
+		})
+		entry.Changes = auditChanges(nil, snapshotVariant(variant))
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.catalog.Invalidate(ctx)
+	return variant, nil
+}
+
+// UpdateVariant replaces a variant's SKU, options and position.
+func (s *AdminService) UpdateVariant(ctx context.Context, variantID string, req UpdateVariantRequest, actorID string, client ClientInfo) (*models.ProductVariant, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.UpdateVariant")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	variant := &models.ProductVariant{ID: variantID, SKU: req.SKU, Options: req.Options, Position: req.Position}
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.adminRepo.FindVariantForUpdate(ctx, variantID)
+		if err != nil {
+			return err
+		}
+		product, err := s.adminRepo.FindProductForUpdate(ctx, before.ProductID)
+		if err != nil {
+			return err
+		}
+		verr := &ValidationError{}
+		checkOptions(product.Options, req.Options, "options", verr)
+		if err := verr.errOrNil(); err != nil {
+			return err
+		}
+
+		if err := s.adminRepo.UpdateVariant(ctx, variant); err != nil {
+			return variantConflict(err)
+		}
+		entry := newAuditEntry(&actorID, "variant.update", "variant", variantID, client, map[string]interface{}{
+			"product_id": before.ProductID,
+		})
+		entry.Changes = auditChanges(snapshotVariant(before), snapshotVariant(variant))
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.catalog.Invalidate(ctx)
+	return variant, nil
+}
+
+// DeleteVariant removes a variant and every cart line holding it. A product's
+// last variant can't be deleted; see ErrLastVariant.
+func (s *AdminService) DeleteVariant(ctx context.Context, variantID, actorID string, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "AdminService.DeleteVariant")
+	defer span.End()
+
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.adminRepo.FindVariantForUpdate(ctx, variantID)
+		if err != nil {
+			return err
+		}
+		// Locking the product and its variants serializes concurrent deletes,
+		// so two of them can't remove the last two variants.
+		product, err := s.adminRepo.FindProductForUpdate(ctx, before.ProductID)
+		if err != nil {
+			return err
+		}
+		if len(product.Variants) <= 1 {
+			return ErrLastVariant
+		}
+		if err := s.adminRepo.DeleteVariant(ctx, variantID); err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "variant.delete", "variant", variantID, client, map[string]interface{}{
+			"product_id": before.ProductID,
+		})
+		entry.Changes = auditChanges(snapshotVariant(before), nil)
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return err
+	}
+	s.catalog.Invalidate(ctx)
+	return nil
+}
+
+// AdjustVariantInventory is AdjustInventory for one variant of a product.
+func (s *AdminService) AdjustVariantInventory(ctx context.Context, variantID string, req AdjustInventoryRequest, actorID string, client ClientInfo) (int, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.AdjustVariantInventory")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return 0, err
+	}
+	change := req.Change
+	var newInventory int
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		newInventory, err = s.adminRepo.AdjustVariantInventory(ctx, variantID, change)
+		if err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "variant.inventory_adjust", "variant", variantID, client, map[string]interface{}{
+			"change": change,
+		})
+		entry.Changes = auditChanges(map[string]int{"inventory_count": newInventory - change}, map[string]int{"inventory_count": newInventory})
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.catalog.Invalidate(ctx)
+
+	direction := "increase"
+	if change < 0 {
+		direction = "decrease"
+	}
+	inventoryAdjustmentsTotal.WithLabelValues(direction).Inc()
+	return newInventory, nil
+}
+
+// ChangeUserRole assigns a user a role. Changing it bumps the user's token
+// version, which revokes their existing tokens, so they have to log in again.
+func (s *AdminService) ChangeUserRole(ctx context.Context, userID string, req ChangeRoleRequest, actorID string, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "AdminService.ChangeUserRole")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	return s.txm.WithTx(ctx, func(ctx context.Context) error {
+		previous, err := s.adminRepo.SetUserRole(ctx, userID, req.Role)
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			verr := &ValidationError{}
+			verr.add("role", "is not a known role")
+			return verr
+		}
+		if err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "user.role_change", "user", userID, client, nil)
+		entry.Changes = auditChanges(map[string]*string{"role": previous}, map[string]*string{"role": &req.Role})
+		return s.logRepo.Append(ctx, entry)
+	})
+}
+
+// ListAuditLog returns a page of audit entries matching q, newest first.
+func (s *AdminService) ListAuditLog(ctx context.Context, q AuditLogQuery) ([]*models.AuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.ListAuditLog")
+	defer span.End()
+
+	filter := repository.AuditFilter{
+		ActorID:    q.ActorID,
+		EntityType: q.EntityType,
+		EntityID:   q.EntityID,
+		Limit:      q.Limit,
+		Offset:     (q.Page - 1) * q.Limit,
+	}
+	verr := &ValidationError{}
+	parse := func(field, value string, t *time.Time) {
+		if value == "" {
+			return
+		}
+		var err error
+		if *t, err = time.Parse(time.RFC3339, value); err != nil {
+			verr.add(field, "must be an RFC 3339 timestamp")
+		}
+	}
+	parse("from", q.From, &filter.From)
+	parse("to", q.To, &filter.To)
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		verr.add("to", "must be after from")
+	}
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
+	}
+
+	return s.logRepo.Find(ctx, filter)
+}
+
+// validateProduct checks the tags of req and how its variants fit its options.
+func validateProduct(req CreateProductRequest) error {
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+	verr := &ValidationError{}
+	checkVariants(req, "", verr)
+	return verr.errOrNil()
+}
+
+// checkVariants adds to verr what is wrong with the options and variants of
+// req, whose fields are named with prefix. SKUs taken by other products are
+// left for the database to report.
+func checkVariants(req CreateProductRequest, prefix string, verr *ValidationError) {
+	for i, name := range req.Options {
+		if strings.TrimSpace(name) == "" {
+			verr.add(prefix+"options", "must not contain blank names")
+		} else if slices.Contains(req.Options[:i], name) {
+			verr.add(prefix+"options", "must not repeat a name")
+		}
+	}
+	if len(req.Options) > 0 && len(req.Variants) == 0 {
+		verr.add(prefix+"variants", "is required when the product has options")
+	}
+
+	skus := make(map[string]bool)
+	for i, v := range req.Variants {
+		field := prefix + "variants[" + strconv.Itoa(i) + "]."
+		checkOptions(req.Options, v.Options, field+"options", verr)
+		if skus[v.SKU] {
+			verr.add(field+"sku", "is used by another variant")
+		}
+		skus[v.SKU] = true
+		for _, other := range req.Variants[:i] {
+			if maps.Equal(other.Options, v.Options) {
+				verr.add(field+"options", "are the same as another variant's")
+			}
+		}
+	}
+}
+
+// checkOptions adds an error for field to verr unless values has a non-blank
+// value for each of a product's options and nothing else.
+func checkOptions(options []string, values map[string]string, field string, verr *ValidationError) {
+	if len(values) != len(options) {
+		verr.add(field, "must have a value for each of the product's options")
+		return
+	}
+	for _, name := range options {
+		if strings.TrimSpace(values[name]) == "" {
+			verr.add(field, "must have a value for each of the product's options")
+			return
+		}
+	}
+}
+
+// variantConflict reports the unique constraints on variants as a
+// ValidationError, passing other errors through.
+func variantConflict(err error) error {
+	verr := &ValidationError{}
+	switch {
+	case errors.Is(err, repository.ErrDuplicateSKU):
+		verr.add("sku", "is already in use")
+	case errors.Is(err, repository.ErrDuplicateVariant):
+		verr.add("options", "are the same as another variant's")
+	default:
+		return err
+	}
+	return verr
+}
+
+// toVariants returns the variants of a new product. Without any listed, the
+// product gets a single variant whose SKU defaults to its ID.
+func (req CreateProductRequest) toVariants(productID string) []*models.ProductVariant {
+	if len(req.Variants) == 0 {
+		sku := req.SKU
+		if sku == "" {
+			sku = productID
+		}
+		return []*models.ProductVariant{
+			{ProductID: productID, SKU: sku, Price: req.Price, InventoryCount: req.InventoryCount},
+		}
+	}
+	variants := make([]*models.ProductVariant, len(req.Variants))
+	for i, v := range req.Variants {
+		variants[i] = v.toVariant(productID, i)
+	}
+	return variants
+}
+
+func (req VariantRequest) toVariant(productID string, position int) *models.ProductVariant {
+	return &models.ProductVariant{
+		ProductID:      productID,
+		SKU:            req.SKU,
+		Options:        req.Options,
 		Price:          req.Price,
 		InventoryCount: req.InventoryCount,
+		Position:       position,
+	}
+}
+
+func (req CreateProductRequest) toProduct() *models.Product {
+	product := &models.Product{
+		Name:    req.Name,
+		Options: req.Options,
 	}
 	if req.CategoryID != nil {
 		product.CategoryID.String = *req.CategoryID
@@ -54,19 +606,13 @@ func (s *AdminService) CreateProduct(ctx context.Context, req CreateProductReque
 		product.Description.String = *req.Description
 		product.Description.Valid = true
 	}
-
-	err := s.adminRepo.CreateProduct(ctx, product)
-	if err != nil {
-		return nil, err
+	if req.BadgeEN != nil {
+		product.BadgeEN.String = *req.BadgeEN
+		product.BadgeEN.Valid = true
 	}
-
-	return product, nil
-}
-
-func (s *AdminService) AdjustInventory(ctx context.Context, productID string, change int) (int, error) {
-	if change == 0 {
-		return 0, errors.New("inventory change cannot be zero")
+	if req.BadgeFI != nil {
+		product.BadgeFI.String = *req.BadgeFI
+		product.BadgeFI.Valid = true
 	}
-	// Future enhancement: check that inventory doesn't go below zero if change is negative.
-	return s.adminRepo.AdjustProductInventory(ctx, productID, change)
+	return product
 }
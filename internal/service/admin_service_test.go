@@ -0,0 +1,170 @@
+// backend/internal/service/admin_service_test.go
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/repository/repotest"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const testAdminID = "11111111-1111-4111-8111-111111111111"
+
+var testClient = ClientInfo{IP: "203.0.113.7", RequestID: "req-1"}
+
+// failingLog rejects every entry, to show that audited changes roll back with it.
+type failingLog struct{}
+
+var errLogUnavailable = errors.New("audit log unavailable")
+
+func (failingLog) Append(ctx context.Context, e *models.AuditEntry) error {
+	return errLogUnavailable
+}
+
+func (failingLog) Find(ctx context.Context, f repository.AuditFilter) ([]*models.AuditEntry, error) {
+	return nil, errLogUnavailable
+}
+
+func TestAdminServiceCreateProduct(t *testing.T) {
+	db := repotest.NewDB()
+	svc := NewAdminService(db.AdminRepository(), db.LogRepository(), db.TxManager(), nil)
+	ctx := context.Background()
+
+	desc := "Dark roast"
+	product, err := svc.CreateProduct(ctx, CreateProductRequest{Name: "Coffee", Description: &desc, Price: 12.5, InventoryCount: 3}, testAdminID, testClient)
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	stored, err := db.ProductRepository().FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.Name != "Coffee" || stored.Description.String != desc {
+		t.Errorf("stored product = %+v", stored)
+	}
+	variants, _ := db.ProductRepository().FindVariants(ctx, []string{product.ID})
+	if len(variants) != 1 || variants[0].SKU != product.ID || variants[0].Price != 12.5 || variants[0].InventoryCount != 3 {
+		t.Errorf("variants = %+v, want one with the product's ID as SKU", variants)
+	}
+
+	entries := db.AuditEntries()
+	if len(entries) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Action != "product.create" || e.EntityID != product.ID || e.ActorID == nil || *e.ActorID != testAdminID ||
+		e.IPAddress != testClient.IP || e.RequestID != testClient.RequestID {
+		t.Errorf("audit entry = %+v", e)
+	}
+	var changes map[string]auditChange
+	if err := json.Unmarshal(e.Changes, &changes); err != nil || string(changes["name"].After) != `"Coffee"` || string(changes["name"].Before) != "null" {
+		t.Errorf("audit changes = %s (%v)", e.Changes, err)
+	}
+}
+
+func TestAdminServiceCreateProductValidation(t *testing.T) {
+	db := repotest.NewDB()
+	svc := NewAdminService(db.AdminRepository(), db.LogRepository(), db.TxManager(), nil)
+
+	_, err := svc.CreateProduct(context.Background(), CreateProductRequest{Price: -1}, testAdminID, testClient)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields["name"] == "" || verr.Fields["price"] == "" {
+		t.Errorf("err = %v, want ValidationErrors for name and price", err)
+	}
+	if len(db.AuditEntries()) != 0 {
+		t.Error("a rejected request was audited")
+	}
+}
+
+func TestAdminServiceRollsBackWithoutAudit(t *testing.T) {
+	db := repotest.NewDB()
+	svc := NewAdminService(db.AdminRepository(), failingLog{}, db.TxManager(), nil)
+	ctx := context.Background()
+
+	if _, err := svc.CreateProduct(ctx, CreateProductRequest{Name: "Coffee", Price: 12.5}, testAdminID, testClient); !errors.Is(err, errLogUnavailable) {
+		t.Errorf("CreateProduct: err = %v, want errLogUnavailable", err)
+	}
+	req := BulkCreateProductsRequest{Products: []CreateProductRequest{{Name: "A", Price: 1}, {Name: "B", Price: 2}}}
+	if _, err := svc.BulkCreateProducts(ctx, req, testAdminID, testClient); !errors.Is(err, errLogUnavailable) {
+		t.Errorf("BulkCreateProducts: err = %v, want errLogUnavailable", err)
+	}
+	if products, _ := db.ProductRepository().FindAll(ctx, 10, 0); len(products) != 0 {
+		t.Errorf("%d products were kept without an audit entry", len(products))
+	}
+
+	// Seed a product directly, then check that a failed adjustment leaves it alone.
+	p := &models.Product{Name: "Tea"}
+	if err := db.AdminRepository().CreateProduct(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AdminRepository().CreateVariants(ctx, []*models.ProductVariant{{ProductID: p.ID, SKU: "TEA", Price: 4, InventoryCount: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AdjustInventory(ctx, p.ID, AdjustInventoryRequest{Change: 3}, testAdminID, testClient); !errors.Is(err, errLogUnavailable) {
+		t.Errorf("AdjustInventory: err = %v, want errLogUnavailable", err)
+	}
+	if variants, _ := db.ProductRepository().FindVariants(ctx, []string{p.ID}); variants[0].InventoryCount != 5 {
+		t.Errorf("inventory = %d, want 5 after the rollback", variants[0].InventoryCount)
+	}
+}
+
+func TestAdminServiceBulkCreateProducts(t *testing.T) {
+	db := repotest.NewDB()
+	svc := NewAdminService(db.AdminRepository(), db.LogRepository(), db.TxManager(), nil)
+	ctx := context.Background()
+
+	req := BulkCreateProductsRequest{Products: []CreateProductRequest{{Name: "A", Price: 1}, {Name: "B", Price: 2}, {Name: "C", Price: 3}}}
+	created, err := svc.BulkCreateProducts(ctx, req, testAdminID, testClient)
+	if err != nil {
+		t.Fatalf("BulkCreateProducts: %v", err)
+	}
+	if created != 3 {
+		t.Errorf("created = %d, want 3", created)
+	}
+	if products, _ := db.ProductRepository().FindAll(ctx, 10, 0); len(products) != 3 {
+		t.Errorf("%d products stored, want 3", len(products))
+	}
+	if entries := db.AuditEntries(); len(entries) != 1 || entries[0].Action != "product.bulk_create" {
+		t.Errorf("audit entries = %+v, want one product.bulk_create", entries)
+	}
+
+	_, err = svc.BulkCreateProducts(ctx, BulkCreateProductsRequest{}, testAdminID, testClient)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("empty import: err = %v, want a ValidationError", err)
+	}
+}
+
+func TestAdminServiceAdjustInventory(t *testing.T) {
+	db := repotest.NewDB()
+	svc := NewAdminService(db.AdminRepository(), db.LogRepository(), db.TxManager(), nil)
+	ctx := context.Background()
+
+	product, err := svc.CreateProduct(ctx, CreateProductRequest{Name: "Coffee", Price: 12.5, InventoryCount: 10}, testAdminID, testClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stock, err := svc.AdjustInventory(ctx, product.ID, AdjustInventoryRequest{Change: -4}, testAdminID, testClient)
+	if err != nil {
+		t.Fatalf("AdjustInventory: %v", err)
+	}
+	if stock != 6 {
+		t.Errorf("stock = %d, want 6", stock)
+	}
+	entries := db.AuditEntries()
+	if last := entries[len(entries)-1]; last.Action != "product.inventory_adjust" || last.EntityID != product.ID {
+		t.Errorf("last audit entry = %+v", last)
+	}
+
+	var verr *ValidationError
+	if _, err := svc.AdjustInventory(ctx, product.ID, AdjustInventoryRequest{}, testAdminID, testClient); !errors.As(err, &verr) {
+		t.Errorf("zero change: err = %v, want a ValidationError", err)
+	}
+	if _, err := svc.AdjustInventory(ctx, "00000000-0000-4000-8000-000000000000", AdjustInventoryRequest{Change: 1}, testAdminID, testClient); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Errorf("unknown product: err = %v, want ErrProductNotFound", err)
+	}
+}
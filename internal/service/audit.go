@@ -0,0 +1,117 @@
+// backend/internal/service/audit.go
+package service
+
+import (
+	"backend/internal/models"
+	"bytes"
+	"encoding/json"
+)
+
+// newAuditEntry builds an audit log entry for an action on an entity. actorID
+// is nil for system or anonymous events.
+func newAuditEntry(actorID *string, action, entityType, entityID string, client ClientInfo, details map[string]interface{}) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IPAddress:  client.IP,
+		RequestID:  client.RequestID,
+	}
+	if details != nil {
+		entry.Details, _ = json.Marshal(details)
+	}
+	return entry
+}
+
+// auditChange is one field's entry in models.AuditEntry.Changes.
+type auditChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// auditChanges returns the fields that differ between before and after, two
+// snapshots of an entity that encode to JSON objects, or nil if none does.
+// before is nil for a creation and after for a deletion, so every field is
+// recorded for those.
+func auditChanges(before, after interface{}) json.RawMessage {
+	old, updated := snapshotFields(before), snapshotFields(after)
+	changes := make(map[string]auditChange)
+	for name, value := range updated {
+		if !bytes.Equal(old[name], value) {
+			changes[name] = auditChange{Before: jsonOrNull(old[name]), After: value}
+		}
+	}
+	for name, value := range old {
+		if _, ok := updated[name]; !ok {
+			changes[name] = auditChange{Before: value, After: jsonOrNull(nil)}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(changes)
+	return data
+}
+
+// snapshotFields encodes v and splits the resulting object into its fields.
+// A nil v, including a nil pointer, has none.
+func snapshotFields(v interface{}) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if data, err := json.Marshal(v); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+func jsonOrNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// productSnapshot is what audit entries record about a product. Variants
+// are only included when the product's were loaded.
+type productSnapshot struct {
+	CategoryID  *string            `json:"category_id"`
+	Name        string             `json:"name"`
+	Description *string            `json:"description"`
+	BadgeEN     *string            `json:"badge_en"`
+	BadgeFI     *string            `json:"badge_fi"`
+	Options     []string           `json:"options"`
+	Variants    []*variantSnapshot `json:"variants,omitempty"`
+}
+
+func snapshotProduct(p *models.Product) *productSnapshot {
+	s := &productSnapshot{Name: p.Name, Options: p.Options}
+	if p.CategoryID.Valid {
+		s.CategoryID = &p.CategoryID.String
+	}
+	if p.Description.Valid {
+		s.Description = &p.Description.String
+	}
+	if p.BadgeEN.Valid {
+		s.BadgeEN = &p.BadgeEN.String
+	}
+	if p.BadgeFI.Valid {
+		s.BadgeFI = &p.BadgeFI.String
+	}
+	for _, v := range p.Variants {
+		s.Variants = append(s.Variants, snapshotVariant(v))
+	}
+	return s
+}
+
+// variantSnapshot is what audit entries record about a variant.
+type variantSnapshot struct {
+	SKU            string            `json:"sku"`
+	Options        map[string]string `json:"options"`
+	Price          float64           `json:"price"`
+	InventoryCount int               `json:"inventory_count"`
+	Position       int               `json:"position"`
+}
+
+func snapshotVariant(v *models.ProductVariant) *variantSnapshot {
+	return &variantSnapshot{SKU: v.SKU, Options: v.Options, Price: v.Price, InventoryCount: v.InventoryCount, Position: v.Position}
+}
@@ -0,0 +1,280 @@
+// backend/internal/service/auth_service.go
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/pkg/auth"
+	"backend/pkg/logging"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is deliberately vague so it doesn't reveal which part was wrong.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// ErrEmailNotVerified is returned by Login for a correct password on an
+// account whose email address hasn't been verified. It is only returned after
+// the password check, so it doesn't reveal which addresses are registered.
+var ErrEmailNotVerified = errors.New("email address is not verified")
+
+// ThrottledError is returned when a login is refused before the password is checked.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // True for a lockout, false for a progressive delay
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts; the account is temporarily locked"
+	}
+	return "too many failed login attempts; please wait before trying again"
+}
+
+// LockoutPolicy controls brute-force protection for logins.
+type LockoutPolicy struct {
+	MaxAccountFailures int           // Failures within Window before an account is locked
+	MaxIPFailures      int           // Failures within Window before a client IP is locked
+	Window             time.Duration // Failures older than this are forgotten
+	LockoutDuration    time.Duration
+	BaseDelay          time.Duration // Delay after the second account failure, doubling each time
+	MaxDelay           time.Duration
+}
+
+// DefaultLockoutPolicy is used for any zero fields of a configured policy.
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxAccountFailures: 5,
+	MaxIPFailures:      50,
+	Window:             15 * time.Minute,
+	LockoutDuration:    15 * time.Minute,
+	BaseDelay:          time.Second,
+	MaxDelay:           30 * time.Second,
+}
+
+// ClientInfo identifies where a request came from, for throttling and auditing.
+type ClientInfo struct {
+	IP        string
+	RequestID string
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type LoginResponse struct {
+	Token string        `json:"token"`
+	User  *UserResponse `json:"user"`
+}
+
+type AuthService struct {
+	userRepo    repository.UserRepository
+	attemptRepo repository.LoginAttemptRepository
+	logRepo     repository.LogRepository
+	tokens      *auth.TokenManager
+	policy      LockoutPolicy
+	dummyHash   []byte // Compared against for unknown emails so response time doesn't leak existence
+}
+
+func NewAuthService(ur repository.UserRepository, ar repository.LoginAttemptRepository, lr repository.LogRepository, tm *auth.TokenManager, policy LockoutPolicy) *AuthService {
+	if policy.MaxAccountFailures <= 0 {
+		policy.MaxAccountFailures = DefaultLockoutPolicy.MaxAccountFailures
+	}
+	if policy.MaxIPFailures <= 0 {
+		policy.MaxIPFailures = DefaultLockoutPolicy.MaxIPFailures
+	}
+	if policy.Window <= 0 {
+		policy.Window = DefaultLockoutPolicy.Window
+	}
+	if policy.LockoutDuration <= 0 {
+		policy.LockoutDuration = DefaultLockoutPolicy.LockoutDuration
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultLockoutPolicy.BaseDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultLockoutPolicy.MaxDelay
+	}
+
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return &AuthService{userRepo: ur, attemptRepo: ar, logRepo: lr, tokens: tm, policy: policy, dummyHash: dummyHash}
+}
+
+// Login checks the credentials and returns a signed token. Attempts for an
+// account or from an IP that is locked or inside its delay are refused with a
+// *ThrottledError without checking the password. Accounts must have verified
+// their email address; until then Login returns ErrEmailNotVerified.
+func (s *AuthService) Login(ctx context.Context, req LoginRequest, client ClientInfo) (*LoginResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	email := normalizeEmail(req.Email)
+	accountKey := "account:" + email
+	ipKey := "ip:" + client.IP
+
+	if err := s.checkThrottle(ctx, accountKey, true); err != nil {
+		return nil, err
+	}
+	if client.IP != "" {
+		if err := s.checkThrottle(ctx, ipKey, false); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	hash := s.dummyHash
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || user == nil {
+		if err := s.recordFailure(ctx, accountKey, ipKey, user, client); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// Only the account counter is cleared; a valid login must not reset the
+	// IP counter, or one known account could be used to keep guessing others.
+	if err := s.attemptRepo.Reset(ctx, accountKey); err != nil {
+		return nil, err
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	token, err := s.tokens.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{Token: token, User: toUserResponse(user)}, nil
+}
+
+// UnlockAccount clears the failed-login state of a user. actorID is the admin performing it.
+func (s *AuthService) UnlockAccount(ctx context.Context, userID, actorID string, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "AuthService.UnlockAccount")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.attemptRepo.Reset(ctx, "account:"+normalizeEmail(user.Email)); err != nil {
+		return err
+	}
+
+	s.audit(ctx, &actorID, "auth.unlock", "user", user.ID, client, nil)
+	return nil
+}
+
+// JWKS returns the public keys clients can use to verify our tokens.
+func (s *AuthService) JWKS() auth.JWKS {
+	return s.tokens.JWKS()
+}
+
+func (s *AuthService) checkThrottle(ctx context.Context, key string, progressive bool) error {
+	ctx, span := tracer.Start(ctx, "AuthService.checkThrottle")
+	defer span.End()
+
+	t, err := s.attemptRepo.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if t.LockedUntil != nil && t.LockedUntil.After(now) {
+		return &ThrottledError{RetryAfter: t.LockedUntil.Sub(now), Locked: true}
+	}
+	if progressive {
+		if next := t.LastFailureAt.Add(s.delayAfter(t.Failures)); next.After(now) {
+			return &ThrottledError{RetryAfter: next.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// delayAfter is the wait required after n consecutive failures: none for the
+// first, then BaseDelay doubling up to MaxDelay.
+func (s *AuthService) delayAfter(failures int) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+	delay := s.policy.BaseDelay
+	for i := 2; i < failures && delay < s.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.policy.MaxDelay {
+		delay = s.policy.MaxDelay
+	}
+	return delay
+}
+
+func (s *AuthService) recordFailure(ctx context.Context, accountKey, ipKey string, user *models.User, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "AuthService.recordFailure")
+	defer span.End()
+
+	until := time.Now().Add(s.policy.LockoutDuration)
+
+	account, err := s.attemptRepo.RecordFailure(ctx, accountKey, s.policy.Window)
+	if err != nil {
+		return err
+	}
+	if account.Failures >= s.policy.MaxAccountFailures {
+		if err := s.attemptRepo.Lock(ctx, accountKey, until); err != nil {
+			return err
+		}
+		// Unknown emails are throttled too, but only real accounts get an audit entry.
+		if user != nil {
+			s.audit(ctx, nil, "auth.lockout", "user", user.ID, client, map[string]interface{}{
+				"failures":     account.Failures,
+				"locked_until": until,
+			})
+		}
+	}
+
+	if client.IP == "" {
+		return nil
+	}
+	ip, err := s.attemptRepo.RecordFailure(ctx, ipKey, s.policy.Window)
+	if err != nil {
+		return err
+	}
+	if ip.Failures >= s.policy.MaxIPFailures {
+		if err := s.attemptRepo.Lock(ctx, ipKey, until); err != nil {
+			return err
+		}
+		s.audit(ctx, nil, "auth.ip_lockout", "ip", client.IP, client, map[string]interface{}{
+			"failures":     ip.Failures,
+			"locked_until": until,
+		})
+	}
+	return nil
+}
+
+// audit records a security event. Failures are logged rather than returned so
+// that a broken audit table doesn't turn into a login outage.
+func (s *AuthService) audit(ctx context.Context, actorID *string, action, entityType, entityID string, client ClientInfo, details map[string]interface{}) {
+	ctx, span := tracer.Start(ctx, "AuthService.audit")
+	defer span.End()
+
+	entry := newAuditEntry(actorID, action, entityType, entityID, client, details)
+	if err := s.logRepo.Append(ctx, entry); err != nil {
+		logging.FromContext(ctx).Error("could not write audit entry",
+			slog.String("action", action),
+			slog.String("entity_type", entityType),
+			slog.String("entity_id", entityID),
+			slog.Any("error", err),
+		)
+	}
+}
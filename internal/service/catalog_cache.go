@@ -0,0 +1,117 @@
+// backend/internal/service/catalog_cache.go
+package service
+
+import (
+	"backend/pkg/cache"
+	"backend/pkg/logging"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// catalogGenerationKey holds the current generation. Every other catalog key
+// includes it, so replacing it drops all cached listings at once without
+// enumerating them, which also works for shared caches.
+const catalogGenerationKey = "catalog:generation"
+
+// CatalogCache is a read-through cache for catalog listings, shared by the
+// CatalogService that reads through it and the AdminService and
+// PricingService that invalidate it. A nil *CatalogCache disables caching.
+//
+// With an in-process cache each instance invalidates only its own entries;
+// other instances serve their copy until the TTL runs out.
+type CatalogCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+func NewCatalogCache(c cache.Cache, ttl time.Duration) *CatalogCache {
+	return &CatalogCache{cache: c, ttl: ttl}
+}
+
+// Invalidate drops every cached listing. Errors are logged rather than
+// returned: by the time it is called the write has been committed, and the
+// entries expire on their own.
+func (c *CatalogCache) Invalidate(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if err := c.cache.Set(ctx, catalogGenerationKey, []byte(newGeneration()), 0); err != nil {
+		logging.FromContext(ctx).Warn("could not invalidate the catalog cache", slog.Any("error", err))
+	}
+}
+
+// generation returns the current generation, starting one if there is none.
+func (c *CatalogCache) generation(ctx context.Context) (string, error) {
+	gen, ok, err := c.cache.Get(ctx, catalogGenerationKey)
+	if err != nil || ok {
+		return string(gen), err
+	}
+	// No TTL: if the entry is evicted anyway, the new generation only costs misses.
+	fresh := newGeneration()
+	return fresh, c.cache.Set(ctx, catalogGenerationKey, []byte(fresh), 0)
+}
+
+func newGeneration() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// cached returns the value cached under key, calling load on a miss and
+// caching what it returns. Concurrent misses for the same key share a single
+// load. Cache errors are logged and treated as misses so the catalog keeps
+// working when a shared cache is down.
+func cached[T any](ctx context.Context, c *CatalogCache, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	if c == nil {
+		return load(ctx)
+	}
+	logger := logging.FromContext(ctx)
+
+	gen, err := c.generation(ctx)
+	if err != nil {
+		logger.Warn("catalog cache unavailable", slog.Any("error", err))
+		catalogCacheRequestsTotal.WithLabelValues("error").Inc()
+		return load(ctx)
+	}
+	key = "catalog:" + gen + ":" + key
+
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		logger.Warn("catalog cache unavailable", slog.Any("error", err))
+	}
+	if ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			catalogCacheRequestsTotal.WithLabelValues("hit").Inc()
+			return v, nil
+		}
+	}
+	catalogCacheRequestsTotal.WithLabelValues("miss").Inc()
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// The load is shared, so one caller going away must not cancel it.
+		ctx := context.WithoutCancel(ctx)
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(v); err == nil {
+			if err := c.cache.Set(ctx, key, data, c.ttl); err != nil {
+				logger.Warn("could not fill the catalog cache", slog.Any("error", err))
+			}
+		}
+		return v, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
@@ -2,65 +2,267 @@
 package service
 
 import (
+	"backend/internal/models"
 	"backend/internal/repository"
+	"backend/pkg/validate"
 	"context"
+	"fmt"
+	"strings"
+	"time"
 )
 
 // ProductResponse is the DTO for a single product sent to the client.
 // We can shape this differently from the database model if needed.
 type ProductResponse struct {
-	ID             string  `json:"id"`
-	CategoryID     *string `json:"category_id,omitempty"`
-	Name           string  `json:"name"`
-	Description    *string `json:"description,omitempty"`
-	Price          float64 `json:"price"`
-	InventoryCount int     `json:"inventory_count"`
+	ID          string   `json:"id"`
+	CategoryID  *string  `json:"category_id,omitempty"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Options     []string `json:"options"`
+	// BadgeEN and BadgeFI label the product, e.g. "New". A product on sale
+	// without a badge of its own gets the sale badge.
+	BadgeEN *string `json:"badge_en,omitempty"`
+	BadgeFI *string `json:"badge_fi,omitempty"`
+	// PriceRange spans the variants' current prices; it is nil without variants.
+	PriceRange *PriceRange `json:"price_range,omitempty"`
+	// OnSale is set when any variant is on sale.
+	OnSale bool `json:"on_sale"`
+	// InventoryCount is the stock of all variants together.
+	InventoryCount int                `json:"inventory_count"`
+	Images         []*ImageResponse   `json:"images,omitempty"`
+	Variants       []*VariantResponse `json:"variants"`
+	// UpdatedAt is the latest change to the product, its variants or images,
+	// including sales that have started or ended since.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// The badges of a product on sale that has none of its own.
+const (
+	saleBadgeEN = "Sale"
+	saleBadgeFI = "Ale"
+)
+
+// PriceRange is the cheapest and dearest variant of a product.
+type PriceRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// VariantResponse is the DTO for one purchasable variant of a product.
+type VariantResponse struct {
+	ID      string            `json:"id"`
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	// Price is what the variant costs now, the sale price during a sale.
+	Price float64 `json:"price"`
+	// RegularPrice is the price without a sale, to show next to Price while
+	// OnSale is set.
+	RegularPrice float64 `json:"regular_price"`
+	OnSale       bool    `json:"on_sale"`
+	// SaleEndsAt is when the running sale ends, unless it runs until ended.
+	SaleEndsAt     *time.Time       `json:"sale_ends_at,omitempty"`
+	InventoryCount int              `json:"inventory_count"`
+	Images         []*ImageResponse `json:"images,omitempty"`
+}
+
+// ImageResponse is the DTO for a product or variant image.
+type ImageResponse struct {
+	URL       string  `json:"url"`
+	AltText   *string `json:"alt_text,omitempty"`
+	IsPrimary bool    `json:"is_primary"`
 }
 
 // CategoryResponse is the DTO for a category.
 type CategoryResponse struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type CatalogService struct {
-	repo repository.ProductRepository
+	repo  repository.ProductRepository
+	cache *CatalogCache
 }
 
-func NewCatalogService(r repository.ProductRepository) *CatalogService {
-	return &CatalogService{repo: r}
+// NewCatalogService returns a CatalogService that reads listings through c,
+// which may be nil to always query the repository.
+func NewCatalogService(r repository.ProductRepository, c *CatalogCache) *CatalogService {
+	return &CatalogService{repo: r, cache: c}
 }
 
 func (s *CatalogService) ListProducts(ctx context.Context, page, limit int) ([]*ProductResponse, error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.ListProducts")
+	defer span.End()
+
+	// Pagination is the only filter so far; new filters or a locale must be part of the key.
+	key := fmt.Sprintf("products:page=%d:limit=%d", page, limit)
+	return cached(ctx, s.cache, key, func(ctx context.Context) ([]*ProductResponse, error) {
+		return s.listProducts(ctx, page, limit)
+	})
+}
+
+func (s *CatalogService) listProducts(ctx context.Context, page, limit int) ([]*ProductResponse, error) {
 	offset := (page - 1) * limit
 	products, err := s.repo.FindAll(ctx, limit, offset)
 	if err != nil {
 		return nil, err
 	}
+	return s.withVariants(ctx, products)
+}
+
+// withVariants loads the variants and images of products, two queries for
+// the lot, and maps them all to response DTOs.
+func (s *CatalogService) withVariants(ctx context.Context, products []*models.Product) ([]*ProductResponse, error) {
+	if len(products) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	variants, err := s.repo.FindVariants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.repo.FindImages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[string][]*models.ProductVariant)
+	for _, v := range variants {
+		byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+	}
+	imagesByProduct := make(map[string][]*models.ProductImage)
+	for _, img := range images {
+		imagesByProduct[img.ProductID] = append(imagesByProduct[img.ProductID], img)
+	}
 
 	// Map DB models to response DTOs
-	var response []*ProductResponse
-	for _, p := range products {
-		res := &ProductResponse{
-			ID:             p.ID,
-			Name:           p.Name,
-			Price:          p.Price,
-			InventoryCount: p.InventoryCount,
+	now := time.Now()
+	response := make([]*ProductResponse, len(products))
+	for i, p := range products {
+		response[i] = newProductResponse(p, byProduct[p.ID], imagesByProduct[p.ID], now)
+	}
+	return response, nil
+}
+
+// GetProduct returns the product with the given ID, or
+// repository.ErrProductNotFound.
+func (s *CatalogService) GetProduct(ctx context.Context, id string) (*ProductResponse, error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.GetProduct")
+	defer span.End()
+
+	// No row has a malformed ID, and Postgres would reject it as a syntax error.
+	if !validate.UUID(id) {
+		return nil, repository.ErrProductNotFound
+	}
+	return cached(ctx, s.cache, "product:"+strings.ToLower(id), func(ctx context.Context) (*ProductResponse, error) {
+		p, err := s.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.withVariants(ctx, []*models.Product{p})
+		if err != nil {
+			return nil, err
 		}
-		if p.CategoryID.Valid {
-			res.CategoryID = &p.CategoryID.String
+		return res[0], nil
+	})
+}
+
+// newProductResponse groups a product's variants, in display order, and
+// images under it, priced as of now. Images of a variant go with that variant.
+func newProductResponse(p *models.Product, variants []*models.ProductVariant, images []*models.ProductImage, now time.Time) *ProductResponse {
+	res := &ProductResponse{
+		ID:        p.ID,
+		Name:      p.Name,
+		Options:   p.Options,
+		Variants:  make([]*VariantResponse, 0, len(variants)),
+		UpdatedAt: p.UpdatedAt,
+	}
+	if res.Options == nil {
+		res.Options = []string{}
+	}
+	if p.CategoryID.Valid {
+		res.CategoryID = &p.CategoryID.String
+	}
+	if p.Description.Valid {
+		res.Description = &p.Description.String
+	}
+	if p.BadgeEN.Valid {
+		res.BadgeEN = &p.BadgeEN.String
+	}
+	if p.BadgeFI.Valid {
+		res.BadgeFI = &p.BadgeFI.String
+	}
+
+	byVariant := make(map[string]*VariantResponse, len(variants))
+	for _, v := range variants {
+		vr := &VariantResponse{
+			ID:             v.ID,
+			SKU:            v.SKU,
+			Options:        v.Options,
+			Price:          v.EffectivePrice(now),
+			RegularPrice:   v.Price,
+			OnSale:         v.OnSale(now),
+			InventoryCount: v.InventoryCount,
 		}
-		if p.Description.Valid {
-			res.Description = &p.Description.String
+		if vr.OnSale {
+			vr.SaleEndsAt = v.Sale.EndsAt
+			res.OnSale = true
+		}
+		res.Variants = append(res.Variants, vr)
+		byVariant[v.ID] = vr
+
+		res.InventoryCount += v.InventoryCount
+		if res.PriceRange == nil {
+			res.PriceRange = &PriceRange{Min: vr.Price, Max: vr.Price}
+		}
+		res.PriceRange.Min = min(res.PriceRange.Min, vr.Price)
+		res.PriceRange.Max = max(res.PriceRange.Max, vr.Price)
+		if v.UpdatedAt.After(res.UpdatedAt) {
+			res.UpdatedAt = v.UpdatedAt
+		}
+		// A sale starting or ending changes the response without a write.
+		if v.Sale != nil {
+			for _, t := range []*time.Time{&v.Sale.StartsAt, v.Sale.EndsAt} {
+				if t != nil && !t.After(now) && t.After(res.UpdatedAt) {
+					res.UpdatedAt = *t
+				}
+			}
 		}
-		response = append(response, res)
+	}
+	if res.OnSale && res.BadgeEN == nil && res.BadgeFI == nil {
+		en, fi := saleBadgeEN, saleBadgeFI
+		res.BadgeEN, res.BadgeFI = &en, &fi
 	}
 
-	return response, nil
+	for _, img := range images {
+		ir := &ImageResponse{URL: img.URL, IsPrimary: img.IsPrimary}
+		if img.AltText.Valid {
+			ir.AltText = &img.AltText.String
+		}
+		if vr := byVariant[img.VariantID.String]; img.VariantID.Valid && vr != nil {
+			vr.Images = append(vr.Images, ir)
+		} else {
+			res.Images = append(res.Images, ir)
+		}
+		if img.UpdatedAt.After(res.UpdatedAt) {
+			res.UpdatedAt = img.UpdatedAt
+		}
+	}
+	return res
 }
 
 func (s *CatalogService) ListCategories(ctx context.Context) ([]*CategoryResponse, error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.ListCategories")
+	defer span.End()
+
+	return cached(ctx, s.cache, "categories", s.listCategories)
+}
+
+func (s *CatalogService) listCategories(ctx context.Context) ([]*CategoryResponse, error) {
 	categories, err := s.repo.FindAllCategories(ctx)
 	if err != nil {
 		return nil, err
@@ -68,7 +270,7 @@ func (s *CatalogService) ListCategories(ctx context.Context) ([]*CategoryRespons
 
 	var response []*CategoryResponse
 	for _, c := range categories {
-		res := &CategoryResponse{ID: c.ID, Name: c.Name}
+		res := &CategoryResponse{ID: c.ID, Name: c.Name, UpdatedAt: c.UpdatedAt}
 		if c.Description.Valid {
 			res.Description = &c.Description.String
 		}
@@ -0,0 +1,79 @@
+// backend/internal/service/catalog_service_test.go
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository/repotest"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCatalogServiceListProducts(t *testing.T) {
+	db := repotest.NewDB()
+	ctx := context.Background()
+	categoryID := db.AddCategory("Drinks", "")
+
+	for i := 0; i < 3; i++ {
+		p := &models.Product{Name: fmt.Sprintf("Product %d", i)}
+		if i == 0 {
+			p.CategoryID.String, p.CategoryID.Valid = categoryID, true
+			p.Description.String, p.Description.Valid = "The oldest", true
+		}
+		if err := db.AdminRepository().CreateProduct(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		v := &models.ProductVariant{ProductID: p.ID, SKU: p.Name, Price: 9.5, InventoryCount: i}
+		if err := db.AdminRepository().CreateVariants(ctx, []*models.ProductVariant{v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewCatalogService(db.ProductRepository(), nil)
+
+	first, err := svc.ListProducts(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	if len(first) != 2 || first[0].Name != "Product 2" || first[1].Name != "Product 1" {
+		t.Fatalf("page 1 = %v, want the two newest products", names(first))
+	}
+	if first[0].CategoryID != nil || first[0].Description != nil {
+		t.Errorf("NULL columns must map to nil, got %+v", first[0])
+	}
+
+	second, err := svc.ListProducts(ctx, 2, 2)
+	if err != nil {
+		t.Fatalf("ListProducts page 2: %v", err)
+	}
+	if len(second) != 1 || second[0].Name != "Product 0" {
+		t.Fatalf("page 2 = %v, want [Product 0]", names(second))
+	}
+	if p := second[0]; p.CategoryID == nil || *p.CategoryID != categoryID || p.Description == nil || *p.Description != "The oldest" {
+		t.Errorf("optional fields were not mapped: %+v", p)
+	}
+}
+
+func TestCatalogServiceListCategories(t *testing.T) {
+	db := repotest.NewDB()
+	db.AddCategory("Tea", "Loose leaf")
+	db.AddCategory("Coffee", "")
+
+	categories, err := NewCatalogService(db.ProductRepository(), nil).ListCategories(context.Background())
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	if len(categories) != 2 || categories[0].Name != "Coffee" || categories[1].Name != "Tea" {
+		t.Fatalf("categories = %+v, want Coffee then Tea", categories)
+	}
+	if categories[0].Description != nil || categories[1].Description == nil || *categories[1].Description != "Loose leaf" {
+		t.Errorf("descriptions were not mapped: %+v, %+v", categories[0], categories[1])
+	}
+}
+
+func names(products []*ProductResponse) []string {
+	var out []string
+	for _, p := range products {
+		out = append(out, p.Name)
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+// backend/internal/service/email_templates.go
+package service
+
+import (
+	"backend/internal/models"
+	"backend/pkg/mailer"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Email templates live in templates/<name>.<lang>.tmpl and define a
+// "subject" and a "body" block. The validity periods mentioned in the
+// templates must be kept in sync with the TTL constants in user_service.go.
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// emailTemplates is keyed by "<name>.<lang>", e.g. "password_reset.fi".
+var emailTemplates = loadEmailTemplates()
+
+func loadEmailTemplates() map[string]*template.Template {
+	files, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+	templates := make(map[string]*template.Template, len(files))
+	for _, f := range files {
+		key := strings.TrimSuffix(path.Base(f), ".tmpl")
+		templates[key] = template.Must(template.ParseFS(templateFS, f))
+	}
+	return templates
+}
+
+// emailData is the data passed to every email template.
+type emailData struct {
+	Name string
+	Link string
+}
+
+// renderEmail builds a message from the template for the given language,
+// falling back to English when the language is unknown.
+func renderEmail(name, lang, to string, data emailData) (mailer.Message, error) {
+	if lang != models.LanguageFI {
+		lang = models.LanguageEN
+	}
+	tmpl, ok := emailTemplates[name+"."+lang]
+	if !ok {
+		return mailer.Message{}, fmt.Errorf("email template %s.%s not found", name, lang)
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return mailer.Message{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    body.String(),
+	}, nil
+}
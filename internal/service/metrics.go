@@ -0,0 +1,39 @@
+// backend/internal/service/metrics.go
+package service
+
+import "backend/pkg/metrics"
+
+// Business counters. They are incremented only after the change has been stored.
+var (
+	registrationsTotal = metrics.NewCounterVec(
+		"registrations_total",
+		"Number of user accounts created.",
+	)
+	cartAddsTotal = metrics.NewCounterVec(
+		"cart_adds_total",
+		"Number of add-to-cart operations.",
+	)
+	cartAddedItemsTotal = metrics.NewCounterVec(
+		"cart_added_items_total",
+		"Number of product units added to carts.",
+	)
+	inventoryAdjustmentsTotal = metrics.NewCounterVec(
+		"inventory_adjustments_total",
+		"Number of inventory adjustments, by direction.",
+		"direction",
+	)
+	priceChangesTotal = metrics.NewCounterVec(
+		"price_changes_total",
+		"Number of regular price changes, by source: admin or schedule.",
+		"source",
+	)
+	catalogCacheRequestsTotal = metrics.NewCounterVec(
+		"catalog_cache_requests_total",
+		"Catalog listing lookups, by result: hit, miss or error.",
+		"result",
+	)
+)
+
+func init() {
+	metrics.MustRegister(registrationsTotal, cartAddsTotal, cartAddedItemsTotal, inventoryAdjustmentsTotal, priceChangesTotal, catalogCacheRequestsTotal)
+}
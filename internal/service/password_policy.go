@@ -0,0 +1,74 @@
+// backend/internal/service/password_policy.go
+package service
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the input length bcrypt actually uses; anything longer
+// is silently ignored, so we reject it instead.
+const bcryptMaxBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// PasswordPolicy describes which passwords are acceptable.
+type PasswordPolicy struct {
+	MinLength int                 // Minimum length in characters (not bytes)
+	breached  map[string]struct{} // Lower-cased passwords that must not be used
+}
+
+// NewPasswordPolicy returns a policy that rejects the built-in list of common
+// passwords plus, when breachedFile is set, every line of that file.
+func NewPasswordPolicy(minLength int, breachedFile string) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{MinLength: minLength, breached: make(map[string]struct{})}
+	p.addList(strings.NewReader(commonPasswords))
+
+	if breachedFile != "" {
+		f, err := os.Open(breachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not open breached password list: %w", err)
+		}
+		defer f.Close()
+		if err := p.addList(f); err != nil {
+			return nil, fmt.Errorf("could not read breached password list: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// addList reads one password per line, skipping blanks and # comments.
+func (p *PasswordPolicy) addList(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Check returns a user-facing reason the password is rejected, or "" if it is acceptable.
+// email is the account's address; a password equal to it is rejected.
+func (p *PasswordPolicy) Check(password, email string) string {
+	switch {
+	case utf8.RuneCountInString(password) < p.MinLength:
+		return fmt.Sprintf("must be at least %d characters", p.MinLength)
+	case len(password) > bcryptMaxBytes:
+		return fmt.Sprintf("must be at most %d bytes", bcryptMaxBytes)
+	case email != "" && strings.EqualFold(password, email):
+		return "must not be the same as your email address"
+	}
+	if _, found := p.breached[strings.ToLower(password)]; found {
+		return "is too common or has appeared in a data breach"
+	}
+	return ""
+}
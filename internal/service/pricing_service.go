@@ -0,0 +1,363 @@
+// backend/internal/service/pricing_service.go
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// dueChangesBatch caps how many scheduled price changes one run of
+// ApplyScheduledChanges applies; the rest wait for the next run.
+const dueChangesBatch = 100
+
+// SetPriceRequest changes a variant's regular price right away.
+type SetPriceRequest struct {
+	Price float64 `json:"price" validate:"min=0"`
+}
+
+// SchedulePriceRequest changes a variant's regular price at EffectiveAt,
+// which must be in the future.
+type SchedulePriceRequest struct {
+	Price       float64   `json:"price" validate:"min=0"`
+	EffectiveAt time.Time `json:"effective_at"`
+}
+
+// SaleRequest puts a variant on sale at Price, which must be below its
+// regular price, from StartsAt until EndsAt. StartsAt defaults to now; without
+// EndsAt the sale runs until it is ended.
+type SaleRequest struct {
+	Price    float64    `json:"price" validate:"min=0"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// PriceHistoryQuery pages through a variant's price history.
+type PriceHistoryQuery struct {
+	Page  int
+	Limit int
+}
+
+// PriceHistoryResponse lists a variant's pending price changes, soonest
+// first, and a page of its past ones, newest first.
+type PriceHistoryResponse struct {
+	Scheduled []*models.ScheduledPriceChange `json:"scheduled"`
+	History   []*models.PriceChange          `json:"history"`
+}
+
+// PricingService manages what variants cost: regular prices, sales and
+// scheduled price changes. Every change of a regular price, whoever makes it,
+// is kept in the price history as well as the audit log.
+type PricingService struct {
+	adminRepo   repository.AdminRepository
+	productRepo repository.ProductRepository
+	priceRepo   repository.PriceRepository
+	logRepo     repository.LogRepository
+	txm         repository.TxManager
+	catalog     *CatalogCache
+
+	mu      sync.Mutex
+	lastRun time.Time // Up to when ApplyScheduledChanges has checked for sales starting or ending
+}
+
+// NewPricingService returns a PricingService. Price changes invalidate
+// catalog, which may be nil.
+func NewPricingService(ar repository.AdminRepository, prod repository.ProductRepository, pr repository.PriceRepository, lr repository.LogRepository, txm repository.TxManager, catalog *CatalogCache) *PricingService {
+	return &PricingService{adminRepo: ar, productRepo: prod, priceRepo: pr, logRepo: lr, txm: txm, catalog: catalog}
+}
+
+// SetPrice changes a variant's regular price and returns the variant.
+// Setting the price it already has changes nothing.
+func (s *PricingService) SetPrice(ctx context.Context, variantID string, req SetPriceRequest, actorID string, client ClientInfo) (*models.ProductVariant, error) {
+	ctx, span := tracer.Start(ctx, "PricingService.SetPrice")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	var variant *models.ProductVariant
+	changed := false
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if variant, err = s.adminRepo.FindVariantForUpdate(ctx, variantID); err != nil {
+			return err
+		}
+		change := &models.PriceChange{VariantID: variantID, NewPrice: req.Price, Source: models.PriceSourceAdmin, ChangedBy: &actorID}
+		if changed, err = s.changePrice(ctx, change); err != nil || !changed {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "variant.price_change", "variant", variantID, client, map[string]interface{}{
+			"product_id": variant.ProductID,
+		})
+		entry.Changes = priceAuditChanges(change)
+		if err := s.logRepo.Append(ctx, entry); err != nil {
+			return err
+		}
+		variant, err = s.adminRepo.FindVariantForUpdate(ctx, variantID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		s.catalog.Invalidate(ctx)
+		priceChangesTotal.WithLabelValues(models.PriceSourceAdmin).Inc()
+	}
+	return variant, nil
+}
+
+// changePrice sets the regular price of change.VariantID to change.NewPrice
+// and records the change in the price history, filling in change. It reports
+// false, recording nothing, if the variant already had that price.
+func (s *PricingService) changePrice(ctx context.Context, change *models.PriceChange) (bool, error) {
+	previous, err := s.priceRepo.SetPrice(ctx, change.VariantID, change.NewPrice)
+	if err != nil || previous == change.NewPrice {
+		return false, err
+	}
+	change.OldPrice = previous
+	return true, s.priceRepo.AppendHistory(ctx, change)
+}
+
+func priceAuditChanges(change *models.PriceChange) []byte {
+	return auditChanges(map[string]float64{"price": change.OldPrice}, map[string]float64{"price": change.NewPrice})
+}
+
+// SchedulePriceChange arranges for a variant's regular price to change later.
+// ApplyScheduledChanges makes the change once its time has come.
+func (s *PricingService) SchedulePriceChange(ctx context.Context, variantID string, req SchedulePriceRequest, actorID string, client ClientInfo) (*models.ScheduledPriceChange, error) {
+	ctx, span := tracer.Start(ctx, "PricingService.SchedulePriceChange")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	verr := &ValidationError{}
+	if req.EffectiveAt.IsZero() {
+		verr.add("effective_at", "is required")
+	} else if !req.EffectiveAt.After(time.Now()) {
+		verr.add("effective_at", "must be in the future")
+	}
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
+	}
+
+	change := &models.ScheduledPriceChange{VariantID: variantID, Price: req.Price, EffectiveAt: req.EffectiveAt, CreatedBy: &actorID}
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.priceRepo.SchedulePriceChange(ctx, change); err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "variant.price_schedule", "variant", variantID, client, map[string]interface{}{
+			"scheduled_change_id": change.ID,
+			"price":               change.Price,
+			"effective_at":        change.EffectiveAt,
+		})
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// CancelPriceChange drops a scheduled price change that hasn't been applied,
+// or returns repository.ErrPriceChangeNotFound.
+func (s *PricingService) CancelPriceChange(ctx context.Context, changeID, actorID string, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "PricingService.CancelPriceChange")
+	defer span.End()
+
+	return s.txm.WithTx(ctx, func(ctx context.Context) error {
+		change, err := s.priceRepo.CancelPriceChange(ctx, changeID)
+		if err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "variant.price_schedule_cancel", "variant", change.VariantID, client, map[string]interface{}{
+			"scheduled_change_id": change.ID,
+			"price":               change.Price,
+			"effective_at":        change.EffectiveAt,
+		})
+		return s.logRepo.Append(ctx, entry)
+	})
+}
+
+// SetSale puts a variant on sale, replacing any sale it had, and returns
+// the variant.
+func (s *PricingService) SetSale(ctx context.Context, variantID string, req SaleRequest, actorID string, client ClientInfo) (*models.ProductVariant, error) {
+	ctx, span := tracer.Start(ctx, "PricingService.SetSale")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sale := &models.Sale{Price: req.Price, StartsAt: now, EndsAt: req.EndsAt}
+	if req.StartsAt != nil {
+		sale.StartsAt = *req.StartsAt
+	}
+	verr := &ValidationError{}
+	if sale.EndsAt != nil && !sale.EndsAt.After(sale.StartsAt) {
+		verr.add("ends_at", "must be after starts_at")
+	} else if sale.EndsAt != nil && !sale.EndsAt.After(now) {
+		verr.add("ends_at", "must be in the future")
+	}
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
+	}
+
+	var variant *models.ProductVariant
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.adminRepo.FindVariantForUpdate(ctx, variantID)
+		if err != nil {
+			return err
+		}
+		if sale.Price >= before.Price {
+			verr.add("price", "must be below the regular price")
+			return verr
+		}
+		if err := s.priceRepo.SetSale(ctx, variantID, sale); err != nil {
+			return err
+		}
+		entry := newAuditEntry(&actorID, "variant.sale_set", "variant", variantID, client, map[string]interface{}{
+			"product_id": before.ProductID,
+		})
+		entry.Changes = auditChanges(map[string]*models.Sale{"sale": before.Sale}, map[string]*models.Sale{"sale": sale})
+		if err := s.logRepo.Append(ctx, entry); err != nil {
+			return err
+		}
+		variant, err = s.adminRepo.FindVariantForUpdate(ctx, variantID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.catalog.Invalidate(ctx)
+	return variant, nil
+}
+
+// EndSale takes a variant off sale. Ending a sale that isn't set does nothing.
+func (s *PricingService) EndSale(ctx context.Context, variantID, actorID string, client ClientInfo) error {
+	ctx, span := tracer.Start(ctx, "PricingService.EndSale")
+	defer span.End()
+
+	ended := false
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.adminRepo.FindVariantForUpdate(ctx, variantID)
+		if err != nil || before.Sale == nil {
+			return err
+		}
+		if err := s.priceRepo.SetSale(ctx, variantID, nil); err != nil {
+			return err
+		}
+		ended = true
+		entry := newAuditEntry(&actorID, "variant.sale_end", "variant", variantID, client, map[string]interface{}{
+			"product_id": before.ProductID,
+		})
+		entry.Changes = auditChanges(map[string]*models.Sale{"sale": before.Sale}, map[string]*models.Sale{"sale": nil})
+		return s.logRepo.Append(ctx, entry)
+	})
+	if err != nil {
+		return err
+	}
+	if ended {
+		s.catalog.Invalidate(ctx)
+	}
+	return nil
+}
+
+// PriceHistory returns a variant's pending price changes and a page of its
+// price history. The history outlives the variant, so a deleted variant's is
+// still listed; repository.ErrVariantNotFound is only returned when there is
+// nothing to list for a variant that doesn't exist.
+func (s *PricingService) PriceHistory(ctx context.Context, variantID string, q PriceHistoryQuery) (*PriceHistoryResponse, error) {
+	ctx, span := tracer.Start(ctx, "PricingService.PriceHistory")
+	defer span.End()
+
+	// Pending changes are read first, so a change applied in between is
+	// listed twice rather than missing from both lists.
+	scheduled, err := s.priceRepo.FindPendingChanges(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.priceRepo.FindHistory(ctx, variantID, q.Limit, (q.Page-1)*q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(scheduled) == 0 && len(history) == 0 {
+		if _, err := s.productRepo.FindVariant(ctx, variantID); err != nil {
+			return nil, err
+		}
+	}
+	res := &PriceHistoryResponse{Scheduled: scheduled, History: history}
+	if res.Scheduled == nil {
+		res.Scheduled = []*models.ScheduledPriceChange{}
+	}
+	if res.History == nil {
+		res.History = []*models.PriceChange{}
+	}
+	return res, nil
+}
+
+// ApplyScheduledChanges applies the scheduled price changes due by now and
+// returns how many it applied. It is run periodically by the price scheduler
+// and also drops the cached catalog when a sale has started or ended since
+// the previous run, as listings show sale prices without any write to
+// invalidate them. Several instances may run it at once; each change is
+// applied by exactly one.
+func (s *PricingService) ApplyScheduledChanges(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "PricingService.ApplyScheduledChanges")
+	defer span.End()
+
+	applied, changed := 0, 0
+	err := s.txm.WithTx(ctx, func(ctx context.Context) error {
+		applied, changed = 0, 0 // The transaction may be retried
+		due, err := s.priceRepo.FindDueChanges(ctx, now, dueChangesBatch)
+		if err != nil {
+			return err
+		}
+		for _, c := range due {
+			change := &models.PriceChange{VariantID: c.VariantID, NewPrice: c.Price, Source: models.PriceSourceSchedule, ScheduledChangeID: &c.ID}
+			ok, err := s.changePrice(ctx, change)
+			if err != nil {
+				return err
+			}
+			if err := s.priceRepo.MarkApplied(ctx, c.ID); err != nil {
+				return err
+			}
+			applied++
+			if !ok {
+				continue
+			}
+			changed++
+			entry := newAuditEntry(nil, "variant.price_change", "variant", c.VariantID, ClientInfo{}, map[string]interface{}{
+				"scheduled_change_id": c.ID,
+			})
+			entry.Changes = priceAuditChanges(change)
+			if err := s.logRepo.Append(ctx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if changed > 0 {
+		priceChangesTotal.WithLabelValues(models.PriceSourceSchedule).Add(float64(changed))
+	}
+
+	// The first run has nothing to compare with and invalidates if any sale
+	// has ever started, which costs no more than a cold cache.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	salesChanged, err := s.priceRepo.SalesChangedBetween(ctx, s.lastRun, now)
+	if err != nil {
+		return applied, err
+	}
+	s.lastRun = now
+	if changed > 0 || salesChanged {
+		s.catalog.Invalidate(ctx)
+	}
+	return applied, nil
+}
@@ -0,0 +1,74 @@
+// backend/internal/service/rate_limit_service.go
+package service
+
+import (
+	"backend/internal/repository"
+	"context"
+	"math"
+	"time"
+)
+
+// RateLimitPolicy allows Requests per Period, refilled continuously, with
+// bursts of up to Requests.
+type RateLimitPolicy struct {
+	Name     string // Part of the bucket key, so each policy has its own buckets
+	Requests int
+	Period   time.Duration
+}
+
+func (p RateLimitPolicy) refillPerSecond() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// RateLimitDecision is the outcome of one request against a policy, with
+// what the RateLimit-* and Retry-After headers need.
+type RateLimitDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request would be allowed; zero when allowed
+}
+
+type RateLimitService struct {
+	repo repository.RateLimitRepository
+}
+
+func NewRateLimitService(r repository.RateLimitRepository) *RateLimitService {
+	return &RateLimitService{repo: r}
+}
+
+// Allow takes a token from the bucket for key under policy.
+func (s *RateLimitService) Allow(ctx context.Context, policy RateLimitPolicy, key string) (*RateLimitDecision, error) {
+	ctx, span := tracer.Start(ctx, "RateLimitService.Allow")
+	defer span.End()
+
+	rate := policy.refillPerSecond()
+	bucket, err := s.repo.Take(ctx, policy.Name+":"+key, policy.Requests, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &RateLimitDecision{
+		Allowed:   bucket.Allowed,
+		Limit:     policy.Requests,
+		Remaining: int(math.Floor(bucket.Tokens)),
+		Reset:     secondsToDuration((float64(policy.Requests) - bucket.Tokens) / rate),
+	}
+	if !d.Allowed {
+		d.RetryAfter = secondsToDuration((1 - bucket.Tokens) / rate)
+	}
+	return d, nil
+}
+
+// PurgeIdleBuckets removes buckets that have not been used for a while.
+func (s *RateLimitService) PurgeIdleBuckets(ctx context.Context, idleFor time.Duration) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RateLimitService.PurgeIdleBuckets")
+	defer span.End()
+
+	return s.repo.DeleteIdle(ctx, time.Now().Add(-idleFor))
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Max(s, 0) * float64(time.Second))
+}
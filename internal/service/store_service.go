@@ -5,12 +5,12 @@ import (
 	"backend/internal/models"
 	"backend/internal/repository"
 	"context"
-	"errors"
 )
 
+// AddItemToCartRequest adds a variant of a product, e.g. its 500 g pack.
 type AddItemToCartRequest struct {
-	ProductID string `json:"product_id"`
-	Quantity  int    `json:"quantity"`
+	VariantID string `json:"variant_id" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"min=1,max=99"`
 }
 
 type CartResponse struct {
@@ -30,23 +30,34 @@ func NewStoreService(r repository.StoreRepository) *StoreService {
 }
 
 func (s *StoreService) AddToCart(ctx context.Context, userID string, req AddItemToCartRequest) error {
-	if req.Quantity <= 0 {
-		return errors.New("quantity must be positive")
+	ctx, span := tracer.Start(ctx, "StoreService.AddToCart")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return err
 	}
 
 	item := &models.CartItem{
 		UserID:    userID,
-		ProductID: req.ProductID,
+		VariantID: req.VariantID,
 		Quantity:  req.Quantity,
 	}
 
-	// Future enhancement: Check if product exists and if there is enough inventory
+	// Future enhancement: Check if the variant exists and if there is enough inventory
 	// using the productRepo before adding to cart.
 
-	return s.repo.UpsertCartItem(ctx, item)
+	if err := s.repo.UpsertCartItem(ctx, item); err != nil {
+		return err
+	}
+	cartAddsTotal.WithLabelValues().Inc()
+	cartAddedItemsTotal.WithLabelValues().Add(float64(req.Quantity))
+	return nil
 }
 
 func (s *StoreService) GetCart(ctx context.Context, userID string) (*CartResponse, error) {
+	ctx, span := tracer.Start(ctx, "StoreService.GetCart")
+	defer span.End()
+
 	items, err := s.repo.FindCartByUser(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -67,6 +78,9 @@ func (s *StoreService) GetCart(ctx context.Context, userID string) (*CartRespons
 	return response, nil
 }
 
-func (s *StoreService) RemoveFromCart(ctx context.Context, userID, productID string) error {
-	return s.repo.DeleteCartItem(ctx, userID, productID)
+func (s *StoreService) RemoveFromCart(ctx context.Context, userID, variantID string) error {
+	ctx, span := tracer.Start(ctx, "StoreService.RemoveFromCart")
+	defer span.End()
+
+	return s.repo.DeleteCartItem(ctx, userID, variantID)
 }
@@ -0,0 +1,106 @@
+// backend/internal/service/store_service_test.go
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository/repotest"
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestStore(t *testing.T) (*StoreService, string, *models.Product, *models.Product) {
+	t.Helper()
+	db := repotest.NewDB()
+	ctx := context.Background()
+
+	user := &models.User{FullName: "Jane Doe", Email: "jane@example.com", PasswordHash: "hash"}
+	if err := db.UserRepository().Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	coffee := &models.Product{Name: "Coffee"}
+	tea := &models.Product{Name: "Tea"}
+	for _, p := range []*models.Product{coffee, tea} {
+		if err := db.AdminRepository().CreateProduct(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	coffee.Variants = []*models.ProductVariant{{ProductID: coffee.ID, SKU: "COF", Price: 12.5, InventoryCount: 10}}
+	tea.Variants = []*models.ProductVariant{{ProductID: tea.ID, SKU: "TEA", Price: 4.25, InventoryCount: 10}}
+	if err := db.AdminRepository().CreateVariants(ctx, append(coffee.Variants, tea.Variants...)); err != nil {
+		t.Fatal(err)
+	}
+	return NewStoreService(db.StoreRepository()), user.ID, coffee, tea
+}
+
+func TestStoreServiceCart(t *testing.T) {
+	svc, userID, coffee, tea := newTestStore(t)
+	ctx := context.Background()
+
+	for _, req := range []AddItemToCartRequest{
+		{VariantID: coffee.Variants[0].ID, Quantity: 2},
+		{VariantID: tea.Variants[0].ID, Quantity: 1},
+		{VariantID: coffee.Variants[0].ID, Quantity: 1},
+	} {
+		if err := svc.AddToCart(ctx, userID, req); err != nil {
+			t.Fatalf("AddToCart(%+v): %v", req, err)
+		}
+	}
+
+	cart, err := svc.GetCart(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if len(cart.Items) != 2 || cart.TotalItems != 4 || cart.TotalPrice != 3*12.5+4.25 {
+		t.Errorf("cart = %d lines, %d items, %.2f total; want 2 lines, 4 items, 41.75", len(cart.Items), cart.TotalItems, cart.TotalPrice)
+	}
+
+	if err := svc.RemoveFromCart(ctx, userID, coffee.Variants[0].ID); err != nil {
+		t.Fatalf("RemoveFromCart: %v", err)
+	}
+	cart, _ = svc.GetCart(ctx, userID)
+	if len(cart.Items) != 1 || cart.Items[0].VariantID != tea.Variants[0].ID || cart.Items[0].ProductID != tea.ID || cart.TotalPrice != 4.25 {
+		t.Errorf("after removing coffee: %+v", cart)
+	}
+}
+
+func TestStoreServiceAddToCartValidation(t *testing.T) {
+	svc, userID, coffee, _ := newTestStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		req   AddItemToCartRequest
+		field string
+	}{
+		{"missing variant", AddItemToCartRequest{Quantity: 1}, "variant_id"},
+		{"malformed variant ID", AddItemToCartRequest{VariantID: "coffee", Quantity: 1}, "variant_id"},
+		{"zero quantity", AddItemToCartRequest{VariantID: coffee.Variants[0].ID}, "quantity"},
+		{"too many", AddItemToCartRequest{VariantID: coffee.Variants[0].ID, Quantity: 100}, "quantity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verr *ValidationError
+			if err := svc.AddToCart(ctx, userID, tt.req); !errors.As(err, &verr) || verr.Fields[tt.field] == "" {
+				t.Errorf("err = %v, want a ValidationError for %s", err, tt.field)
+			}
+		})
+	}
+
+	cart, _ := svc.GetCart(ctx, userID)
+	if len(cart.Items) != 0 {
+		t.Errorf("invalid requests added %d cart lines", len(cart.Items))
+	}
+}
+
+func TestStoreServiceEmptyCart(t *testing.T) {
+	svc, userID, _, _ := newTestStore(t)
+
+	cart, err := svc.GetCart(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if len(cart.Items) != 0 || cart.TotalItems != 0 || cart.TotalPrice != 0 {
+		t.Errorf("empty cart = %+v", cart)
+	}
+}
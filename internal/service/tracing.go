@@ -0,0 +1,8 @@
+// backend/internal/service/tracing.go
+package service
+
+import "go.opentelemetry.io/otel"
+
+// tracer creates one span per service method, between the HTTP request span
+// and the repository query spans.
+var tracer = otel.Tracer("backend/internal/service")
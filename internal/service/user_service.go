@@ -4,43 +4,143 @@ package service
 import (
 	"backend/internal/models"
 	"backend/internal/repository"
+	"backend/pkg/auth"
+	"backend/pkg/logging"
+	"backend/pkg/mailer"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
 	// We'd use a real password hashing library here
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidPassword is returned when a password confirmation does not match.
+var ErrInvalidPassword = errors.New("current password is incorrect")
+
+// ErrEmailInUse is returned when registering an address that already has an account.
+var ErrEmailInUse = errors.New("email already in use")
+
+// ErrInvalidToken is returned for a bearer token that is malformed, expired,
+// for a deleted user or issued before the user's last password change.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Lifetimes of emailed tokens. The email templates state these periods too.
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
 // DTOs (Data Transfer Objects) for requests and responses
 type CreateUserRequest struct {
-	FullName string `json:"full_name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	FullName string `json:"full_name" validate:"required,max=200"`
+	Email    string `json:"email" validate:"required,max=254,email"`
+	Password string `json:"password" validate:"required"` // The rest is up to the PasswordPolicy
+}
+
+// UpdateProfileRequest is a partial update; nil fields are left unchanged.
+// An empty Phone clears the stored number.
+type UpdateProfileRequest struct {
+	FullName          *string `json:"full_name" validate:"required,max=200"`
+	Phone             *string `json:"phone" validate:"max=30"`
+	PreferredLanguage *string `json:"preferred_language" validate:"oneof=en fi"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// EmailRequest is used by the endpoints that email a token to an address.
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,max=254,email"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required,max=128"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
 
 type UserResponse struct {
-	ID        string `json:"id"`
-	FullName  string `json:"full_name"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"created_at"`
+	ID                string  `json:"id"`
+	FullName          string  `json:"full_name"`
+	Email             string  `json:"email"`
+	Phone             *string `json:"phone,omitempty"`
+	Role              *string `json:"role,omitempty"`
+	PreferredLanguage string  `json:"preferred_language,omitempty"`
+	EmailVerified     bool    `json:"email_verified"`
+	CreatedAt         string  `json:"created_at"`
+}
+
+// UserServiceOptions holds the non-repository settings of the UserService.
+type UserServiceOptions struct {
+	AppBaseURL     string // Storefront URL that hosts the verify/reset pages linked from emails
+	BcryptCost     int    // Falls back to bcrypt.DefaultCost when out of range
+	PasswordPolicy *PasswordPolicy
+	Tokens         *auth.TokenManager
 }
 
 type UserService struct {
-	repo repository.UserRepository
+	repo       repository.UserRepository
+	tokenRepo  repository.UserTokenRepository
+	txm        repository.TxManager
+	mailer     mailer.Mailer
+	appBaseURL string
+	bcryptCost int
+	policy     *PasswordPolicy
+	tokens     *auth.TokenManager
 }
 
-func NewUserService(r repository.UserRepository) *UserService {
-	return &UserService{repo: r}
+func NewUserService(r repository.UserRepository, tr repository.UserTokenRepository, txm repository.TxManager, m mailer.Mailer, opts UserServiceOptions) *UserService {
+	cost := opts.BcryptCost
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &UserService{
+		repo:       r,
+		tokenRepo:  tr,
+		txm:        txm,
+		mailer:     m,
+		appBaseURL: strings.TrimRight(opts.AppBaseURL, "/"),
+		bcryptCost: cost,
+		policy:     opts.PasswordPolicy,
+		tokens:     opts.Tokens,
+	}
 }
 
 func (s *UserService) Create(ctx context.Context, req CreateUserRequest) (*UserResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Create")
+	defer span.End()
+
+	req.normalize()
+	if err := req.validate(s.policy); err != nil {
+		return nil, err
+	}
+
 	// Business logic: check if user exists
 	if _, err := s.repo.FindByEmail(ctx, req.Email); err == nil {
-		return nil, errors.New("email already in use")
+		return nil, ErrEmailInUse
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
 	}
 
 	// Business logic: hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.bcryptCost)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
@@ -56,28 +156,340 @@ func (s *UserService) Create(ctx context.Context, req CreateUserRequest) (*UserR
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	registrationsTotal.WithLabelValues().Inc()
 
-	// Map the internal model to a public-facing response (omitting the password hash)
-	response := &UserResponse{
-		ID:        user.ID,
-		FullName:  user.FullName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.String(),
+	// The account exists even if the email fails; the user can request a new link.
+	if err := s.sendEmailVerification(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("could not send verification email", slog.String("user_id", user.ID), slog.Any("error", err))
 	}
-	return response, nil
+
+	// Map the internal model to a public-facing response (omitting the password hash)
+	return toUserResponse(user), nil
 }
 
 func (s *UserService) GetByID(ctx context.Context, id string) (*UserResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetByID")
+	defer span.End()
+
 	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err // Let handler decide on 404
 	}
 
-	response := &UserResponse{
-		ID:        user.ID,
-		FullName:  user.FullName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.String(),
+	return toUserResponse(user), nil
+}
+
+// Authenticate validates a bearer token and returns the user it was issued
+// to, or ErrInvalidToken. Changing the password bumps the user's token
+// version, which invalidates every token issued before.
+func (s *UserService) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Authenticate")
+	defer span.End()
+
+	claims, err := s.tokens.ValidateToken(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.repo.FindByID(ctx, claims.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, ErrInvalidToken
+	}
+	return user, nil
+}
+
+// UpdateProfile applies the non-nil fields of req to the user's profile.
+func (s *UserService) UpdateProfile(ctx context.Context, id string, req UpdateProfileRequest) (*UserResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateProfile")
+	defer span.End()
+
+	req.normalize()
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FullName != nil {
+		user.FullName = *req.FullName
+	}
+	if req.Phone != nil {
+		if *req.Phone == "" {
+			user.Phone = nil
+		} else {
+			user.Phone = req.Phone
+		}
+	}
+	if req.PreferredLanguage != nil {
+		user.PreferredLanguage = *req.PreferredLanguage
+	}
+
+	if err := s.repo.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+	return toUserResponse(user), nil
+}
+
+// ChangePassword verifies the current password and stores the new one.
+// Bumping the token version invalidates every previously issued token, so a
+// fresh token is returned for the caller's own session.
+func (s *UserService) ChangePassword(ctx context.Context, id string, req ChangePasswordRequest) (string, error) {
+	ctx, span := tracer.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return "", err
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return "", ErrInvalidPassword
+	}
+	if msg := s.policy.Check(req.NewPassword, user.Email); msg != "" {
+		return "", &ValidationError{Fields: map[string]string{"new_password": msg}}
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return "", &ValidationError{Fields: map[string]string{"new_password": "must differ from the current password"}}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.bcryptCost)
+	if err != nil {
+		return "", errors.New("failed to hash password")
+	}
+
+	user.TokenVersion, err = s.repo.UpdatePassword(ctx, id, string(hashedPassword))
+	if err != nil {
+		return "", err
+	}
+
+	return s.tokens.GenerateToken(user)
+}
+
+// DeleteAccount permanently removes the user after confirming their password.
+func (s *UserService) DeleteAccount(ctx context.Context, id string, req DeleteAccountRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteAccount")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return ErrInvalidPassword
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+// RequestEmailVerification emails a new verification link. It reports success
+// for unknown or already verified addresses so callers can't probe for accounts.
+func (s *UserService) RequestEmailVerification(ctx context.Context, req EmailRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.RequestEmailVerification")
+	defer span.End()
+
+	req.Email = normalizeEmail(req.Email)
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByEmail(ctx, req.Email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.sendEmailVerification(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("could not send verification email", slog.String("user_id", user.ID), slog.Any("error", err))
+	}
+	return nil
+}
+
+// VerifyEmail consumes a verification token and marks the user's email as verified.
+func (s *UserService) VerifyEmail(ctx context.Context, req VerifyEmailRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	// The token is only used up if the user is actually marked as verified.
+	return s.txm.WithTx(ctx, func(ctx context.Context) error {
+		token, err := s.tokenRepo.Consume(ctx, models.TokenPurposeEmailVerification, hashToken(req.Token))
+		if err != nil {
+			return err
+		}
+		return s.repo.MarkEmailVerified(ctx, token.UserID)
+	})
+}
+
+// RequestPasswordReset emails a password reset link. Like RequestEmailVerification
+// it does not reveal whether the address belongs to an account.
+func (s *UserService) RequestPasswordReset(ctx context.Context, req EmailRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.RequestPasswordReset")
+	defer span.End()
+
+	req.Email = normalizeEmail(req.Email)
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByEmail(ctx, req.Email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	link, err := s.issueToken(ctx, user.ID, models.TokenPurposePasswordReset, passwordResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+	if err := s.sendEmail(ctx, "password_reset", user, link); err != nil {
+		logging.FromContext(ctx).Error("could not send password reset email", slog.String("user_id", user.ID), slog.Any("error", err))
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset token and sets the new password. Existing
+// sessions are invalidated the same way as in ChangePassword.
+func (s *UserService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	// The email isn't known until the token is consumed, so that check is skipped here.
+	if msg := s.policy.Check(req.NewPassword, ""); msg != "" {
+		return &ValidationError{Fields: map[string]string{"new_password": msg}}
+	}
+
+	// Hashing is slow, so it happens before the transaction opens.
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.bcryptCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	return s.txm.WithTx(ctx, func(ctx context.Context) error {
+		token, err := s.tokenRepo.Consume(ctx, models.TokenPurposePasswordReset, hashToken(req.Token))
+		if err != nil {
+			return err
+		}
+		if _, err := s.repo.UpdatePassword(ctx, token.UserID, string(hashedPassword)); err != nil {
+			return err
+		}
+		// Receiving the email proves ownership of the address.
+		return s.repo.MarkEmailVerified(ctx, token.UserID)
+	})
+}
+
+// PurgeExpiredTokens deletes verification and reset tokens that can no longer be used.
+func (s *UserService) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "UserService.PurgeExpiredTokens")
+	defer span.End()
+
+	return s.tokenRepo.DeleteExpired(ctx)
+}
+
+func (s *UserService) sendEmailVerification(ctx context.Context, user *models.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.sendEmailVerification")
+	defer span.End()
+
+	link, err := s.issueToken(ctx, user.ID, models.TokenPurposeEmailVerification, emailVerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	return s.sendEmail(ctx, "verify_email", user, link)
+}
+
+// issueToken replaces any outstanding token of the same purpose and returns
+// the storefront link that carries the raw token.
+func (s *UserService) issueToken(ctx context.Context, userID, purpose string, ttl time.Duration, path string) (string, error) {
+	ctx, span := tracer.Start(ctx, "UserService.issueToken")
+	defer span.End()
+
+	raw, err := newRawToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.tokenRepo.DeleteForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+	token := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return "", err
+	}
+
+	return s.appBaseURL + path + "?token=" + url.QueryEscape(raw), nil
+}
+
+func (s *UserService) sendEmail(ctx context.Context, templateName string, user *models.User, link string) error {
+	ctx, span := tracer.Start(ctx, "UserService.sendEmail")
+	defer span.End()
+
+	msg, err := renderEmail(templateName, user.PreferredLanguage, user.Email, emailData{Name: user.FullName, Link: link})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// newRawToken returns 32 random bytes encoded for use in a URL.
+func newRawToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what gets stored, so a leaked user_tokens table can't be replayed.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// toUserResponse maps the internal model to a public-facing response (omitting the password hash).
+func toUserResponse(user *models.User) *UserResponse {
+	return &UserResponse{
+		ID:                user.ID,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Phone:             user.Phone,
+		Role:              user.Role,
+		PreferredLanguage: user.PreferredLanguage,
+		EmailVerified:     user.EmailVerifiedAt != nil,
+		CreatedAt:         user.CreatedAt.String(),
 	}
-	return response, nil
 }
@@ -0,0 +1,243 @@
+// backend/internal/service/user_service_test.go
+package service
+
+import (
+	"backend/internal/repository"
+	"backend/internal/repository/repotest"
+	"backend/pkg/auth"
+	"backend/pkg/mailer"
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recordingMailer keeps every message instead of sending it.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var tokenLink = regexp.MustCompile(`token=(\S+)`)
+
+// lastToken returns the raw token from the newest email sent to addr.
+func (m *recordingMailer) lastToken(t *testing.T, addr string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To != addr {
+			continue
+		}
+		match := tokenLink.FindStringSubmatch(m.sent[i].Body)
+		if match == nil {
+			t.Fatalf("email to %s has no token link:\n%s", addr, m.sent[i].Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("unescaping token: %v", err)
+		}
+		return token
+	}
+	t.Fatalf("no email was sent to %s", addr)
+	return ""
+}
+
+const testPassword = "correct horse battery"
+
+func newTestUserService(t *testing.T) (*UserService, *repotest.DB, *recordingMailer) {
+	t.Helper()
+	policy, err := NewPasswordPolicy(12, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{SigningKey: key, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := repotest.NewDB()
+	m := &recordingMailer{}
+	svc := NewUserService(db.UserRepository(), db.UserTokenRepository(), db.TxManager(), m, UserServiceOptions{
+		AppBaseURL:     "https://shop.example.com",
+		BcryptCost:     bcrypt.MinCost,
+		PasswordPolicy: policy,
+		Tokens:         tokens,
+	})
+	return svc, db, m
+}
+
+func mustRegister(t *testing.T, svc *UserService, email string) *UserResponse {
+	t.Helper()
+	user, err := svc.Create(context.Background(), CreateUserRequest{FullName: "Jane Doe", Email: email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return user
+}
+
+func TestUserServiceCreate(t *testing.T) {
+	svc, _, m := newTestUserService(t)
+	ctx := context.Background()
+
+	user, err := svc.Create(ctx, CreateUserRequest{FullName: "  Jane Doe ", Email: " Jane@Example.COM", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if user.Email != "jane@example.com" || user.FullName != "Jane Doe" || user.EmailVerified {
+		t.Errorf("Create = %+v, want a normalized, unverified user", user)
+	}
+	if len(m.sent) != 1 || m.sent[0].To != "jane@example.com" {
+		t.Errorf("sent %d emails, want one verification email to jane@example.com", len(m.sent))
+	}
+
+	if _, err := svc.Create(ctx, CreateUserRequest{FullName: "Other", Email: "JANE@example.com", Password: testPassword}); !errors.Is(err, ErrEmailInUse) {
+		t.Errorf("Create with an email that is already in use = %v, want ErrEmailInUse", err)
+	}
+
+	_, err = svc.Create(ctx, CreateUserRequest{FullName: "Short", Email: "short@example.com", Password: "short"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields["password"] == "" {
+		t.Errorf("Create with a short password: err = %v, want a password ValidationError", err)
+	}
+}
+
+func TestUserServiceVerifyEmail(t *testing.T) {
+	svc, _, m := newTestUserService(t)
+	ctx := context.Background()
+	user := mustRegister(t, svc, "jane@example.com")
+
+	token := m.lastToken(t, "jane@example.com")
+	if err := svc.VerifyEmail(ctx, VerifyEmailRequest{Token: token}); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	got, err := svc.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !got.EmailVerified {
+		t.Error("email is not verified")
+	}
+
+	if err := svc.VerifyEmail(ctx, VerifyEmailRequest{Token: token}); !errors.Is(err, repository.ErrTokenInvalid) {
+		t.Errorf("reusing the token: err = %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestUserServiceUpdateProfile(t *testing.T) {
+	svc, _, _ := newTestUserService(t)
+	ctx := context.Background()
+	user := mustRegister(t, svc, "jane@example.com")
+
+	phone, lang := " +358 40 123 4567 ", "FI"
+	got, err := svc.UpdateProfile(ctx, user.ID, UpdateProfileRequest{Phone: &phone, PreferredLanguage: &lang})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if got.FullName != "Jane Doe" || got.Phone == nil || *got.Phone != "+358 40 123 4567" || got.PreferredLanguage != "fi" {
+		t.Errorf("UpdateProfile = %+v", got)
+	}
+
+	empty := ""
+	got, err = svc.UpdateProfile(ctx, user.ID, UpdateProfileRequest{Phone: &empty})
+	if err != nil {
+		t.Fatalf("UpdateProfile clearing phone: %v", err)
+	}
+	if got.Phone != nil {
+		t.Errorf("phone = %q, want cleared", *got.Phone)
+	}
+
+	if _, err := svc.UpdateProfile(ctx, "00000000-0000-4000-8000-000000000000", UpdateProfileRequest{}); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestUserServiceChangePassword(t *testing.T) {
+	svc, db, _ := newTestUserService(t)
+	ctx := context.Background()
+	user := mustRegister(t, svc, "jane@example.com")
+
+	_, err := svc.ChangePassword(ctx, user.ID, ChangePasswordRequest{CurrentPassword: "wrong password", NewPassword: "another good passphrase"})
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("wrong current password: err = %v, want ErrInvalidPassword", err)
+	}
+
+	token, err := svc.ChangePassword(ctx, user.ID, ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: "another good passphrase"})
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	claims, err := svc.tokens.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	stored, _ := db.UserRepository().FindByID(ctx, user.ID)
+	if claims.TokenVersion != 1 || stored.TokenVersion != 1 {
+		t.Errorf("token version: claims %d, stored %d; want 1", claims.TokenVersion, stored.TokenVersion)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("another good passphrase")) != nil {
+		t.Error("the new password was not stored")
+	}
+}
+
+func TestUserServiceResetPassword(t *testing.T) {
+	svc, db, m := newTestUserService(t)
+	ctx := context.Background()
+	user := mustRegister(t, svc, "jane@example.com")
+
+	// Unknown addresses succeed silently.
+	if err := svc.RequestPasswordReset(ctx, EmailRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("RequestPasswordReset for an unknown address: %v", err)
+	}
+	if err := svc.RequestPasswordReset(ctx, EmailRequest{Email: "jane@example.com"}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	token := m.lastToken(t, "jane@example.com")
+
+	if err := svc.ResetPassword(ctx, ResetPasswordRequest{Token: token, NewPassword: "a brand new passphrase"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	stored, _ := db.UserRepository().FindByID(ctx, user.ID)
+	if bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("a brand new passphrase")) != nil {
+		t.Error("the new password was not stored")
+	}
+	// Receiving the reset email proves the address, and old sessions end.
+	if stored.EmailVerifiedAt == nil || stored.TokenVersion != 1 {
+		t.Errorf("verified %v, token version %d; want verified and version 1", stored.EmailVerifiedAt, stored.TokenVersion)
+	}
+
+	if err := svc.ResetPassword(ctx, ResetPasswordRequest{Token: token, NewPassword: "yet another passphrase"}); !errors.Is(err, repository.ErrTokenInvalid) {
+		t.Errorf("reusing the token: err = %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestUserServiceDeleteAccount(t *testing.T) {
+	svc, _, _ := newTestUserService(t)
+	ctx := context.Background()
+	user := mustRegister(t, svc, "jane@example.com")
+
+	if err := svc.DeleteAccount(ctx, user.ID, DeleteAccountRequest{Password: "wrong password"}); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("wrong password: err = %v, want ErrInvalidPassword", err)
+	}
+	if err := svc.DeleteAccount(ctx, user.ID, DeleteAccountRequest{Password: testPassword}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if _, err := svc.GetByID(ctx, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("GetByID after delete: err = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,97 @@
+// backend/internal/service/validation.go
+package service
+
+import (
+	"backend/pkg/validate"
+	"sort"
+	"strings"
+)
+
+// ValidationError reports invalid request fields, keyed by JSON field name.
+type ValidationError struct {
+	Fields map[string]string `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+" "+e.Fields[name])
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// add records the first problem found for a field.
+func (e *ValidationError) add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	if _, exists := e.Fields[field]; !exists {
+		e.Fields[field] = message
+	}
+}
+
+// errOrNil lets callers return the collected errors without a typed-nil trap.
+func (e *ValidationError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validateRequest checks the `validate` tags of a request DTO (see package validate).
+func validateRequest(req interface{}) error {
+	fields, err := validate.Struct(req)
+	if err != nil || fields == nil {
+		return err
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// normalizeEmail trims and lower-cases an address so lookups and uniqueness are consistent.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalize trims the name and normalizes the email in place.
+func (req *CreateUserRequest) normalize() {
+	req.FullName = strings.TrimSpace(req.FullName)
+	req.Email = normalizeEmail(req.Email)
+}
+
+// normalize trims the fields that are set and lower-cases the language.
+func (req *UpdateProfileRequest) normalize() {
+	trim := func(s *string) {
+		if s != nil {
+			*s = strings.TrimSpace(*s)
+		}
+	}
+	trim(req.FullName)
+	trim(req.Phone)
+	trim(req.PreferredLanguage)
+	if req.PreferredLanguage != nil {
+		*req.PreferredLanguage = strings.ToLower(*req.PreferredLanguage)
+	}
+}
+
+// validate checks a normalized request's tags and then the password policy,
+// which depends on configuration and so cannot be a tag.
+func (req *CreateUserRequest) validate(policy *PasswordPolicy) error {
+	fields, err := validate.Struct(req)
+	if err != nil {
+		return err
+	}
+	verr := &ValidationError{}
+	for field, msg := range fields {
+		verr.add(field, msg)
+	}
+	if msg := policy.Check(req.Password, req.Email); msg != "" {
+		verr.add("password", msg)
+	}
+	return verr.errOrNil()
+}
@@ -0,0 +1,28 @@
+CREATE TABLE roles (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('customer', 'Regular storefront customer'),
+    ('admin', 'Store administrator with access to /admin routes');
+
+CREATE TABLE users (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    full_name          TEXT NOT NULL,
+    email              TEXT NOT NULL,
+    password_hash      TEXT NOT NULL,
+    phone              TEXT,
+    role_id            UUID REFERENCES roles (id) ON DELETE SET NULL,
+    preferred_language TEXT NOT NULL DEFAULT 'en' CHECK (preferred_language IN ('en', 'fi')),
+    token_version      INTEGER NOT NULL DEFAULT 0,
+    email_verified_at  TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Emails are compared case-insensitively (see UserRepository.FindByEmail).
+CREATE UNIQUE INDEX users_email_lower_idx ON users (lower(email));
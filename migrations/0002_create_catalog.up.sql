@@ -0,0 +1,53 @@
+CREATE TABLE categories (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name           TEXT NOT NULL,
+    description    TEXT,
+    name_en        TEXT,
+    name_fi        TEXT,
+    description_en TEXT,
+    description_fi TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE products (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    category_id     UUID REFERENCES categories (id) ON DELETE SET NULL,
+    name            TEXT NOT NULL,
+    description     TEXT,
+    price           NUMERIC(12, 2) NOT NULL CHECK (price >= 0),
+    inventory_count INTEGER NOT NULL DEFAULT 0,
+    name_en         TEXT,
+    name_fi         TEXT,
+    description_en  TEXT,
+    description_fi  TEXT,
+    origin_en       TEXT,
+    origin_fi       TEXT,
+    unit_en         TEXT,
+    unit_fi         TEXT,
+    badge_en        TEXT,
+    badge_fi        TEXT,
+    features_en     JSONB,
+    features_fi     JSONB,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX products_category_id_idx ON products (category_id);
+CREATE INDEX products_created_at_idx ON products (created_at DESC);
+
+CREATE TABLE product_images (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    url        TEXT NOT NULL,
+    alt_text   TEXT,
+    alt_en     TEXT,
+    alt_fi     TEXT,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX product_images_product_id_idx ON product_images (product_id);
+-- At most one primary image per product.
+CREATE UNIQUE INDEX product_images_primary_idx ON product_images (product_id) WHERE is_primary;
@@ -0,0 +1,8 @@
+CREATE TABLE cart_items (
+    user_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    quantity   INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, product_id)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE login_attempts (
+    key             TEXT PRIMARY KEY,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ
+);
+
+-- actor_id deliberately has no foreign key: audit entries outlive deleted users.
+CREATE TABLE audit_log (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id    TEXT,
+    action      TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id   TEXT NOT NULL,
+    ip_address  TEXT,
+    request_id  TEXT,
+    details     JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at DESC);
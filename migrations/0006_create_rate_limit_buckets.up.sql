@@ -0,0 +1,11 @@
+-- Token buckets for the API rate limiter when RATE_LIMIT_STORE=postgres.
+-- allowed records whether the most recent take succeeded, so a single upsert
+-- can both refill/consume and report the outcome.
+CREATE TABLE rate_limit_buckets (
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+DROP INDEX IF EXISTS audit_log_entity_created_at_idx;
+DROP INDEX IF EXISTS audit_log_actor_id_created_at_idx;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS changes;
@@ -0,0 +1,19 @@
+-- changes holds the before/after values of each field an admin action
+-- changed: {"price": {"before": 12.5, "after": 11.75}}.
+ALTER TABLE audit_log ADD COLUMN changes JSONB;
+
+-- For GET /admin/audit, which filters by actor or entity, newest first.
+CREATE INDEX audit_log_actor_id_created_at_idx ON audit_log (actor_id, created_at DESC);
+CREATE INDEX audit_log_entity_created_at_idx ON audit_log (entity_type, entity_id, created_at DESC);
+
+-- The audit log is append-only: reject updates and deletes outright rather
+-- than relying on every caller to behave.
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,33 @@
+-- Products get their first variant's price and stock back; carts keep one
+-- line per product, merging the quantities of its variants.
+ALTER TABLE products
+    ADD COLUMN price NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    ADD COLUMN inventory_count INTEGER NOT NULL DEFAULT 0;
+UPDATE products p
+SET price = v.price, inventory_count = v.inventory_count
+FROM (
+    SELECT DISTINCT ON (product_id) product_id, price, inventory_count
+    FROM product_variants
+    ORDER BY product_id, position, created_at
+) v
+WHERE v.product_id = p.id;
+ALTER TABLE products ALTER COLUMN price DROP DEFAULT;
+
+CREATE TABLE cart_items_by_product AS
+SELECT ci.user_id, v.product_id, SUM(ci.quantity)::integer AS quantity, MIN(ci.created_at) AS created_at, MAX(ci.updated_at) AS updated_at
+FROM cart_items ci
+JOIN product_variants v ON v.id = ci.variant_id
+GROUP BY ci.user_id, v.product_id;
+DELETE FROM cart_items;
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_pkey;
+ALTER TABLE cart_items DROP COLUMN variant_id;
+ALTER TABLE cart_items ADD COLUMN product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE cart_items ADD PRIMARY KEY (user_id, product_id);
+INSERT INTO cart_items (user_id, product_id, quantity, created_at, updated_at)
+SELECT user_id, product_id, quantity, created_at, updated_at FROM cart_items_by_product;
+DROP TABLE cart_items_by_product;
+
+DROP INDEX IF EXISTS product_images_variant_id_idx;
+ALTER TABLE product_images DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS product_variants;
+ALTER TABLE products DROP COLUMN IF EXISTS options;
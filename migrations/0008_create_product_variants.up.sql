@@ -0,0 +1,39 @@
+-- Variants are what customers buy: one per pack size, weight and so on, each
+-- with its own SKU, price and stock. options names the axes a product's
+-- variants differ along, e.g. {size}; each variant sets a value for every
+-- axis, e.g. {"size": "500 g"}.
+ALTER TABLE products ADD COLUMN options TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE product_variants (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku             TEXT NOT NULL,
+    options         JSONB NOT NULL DEFAULT '{}',
+    price           NUMERIC(12, 2) NOT NULL CHECK (price >= 0),
+    inventory_count INTEGER NOT NULL DEFAULT 0,
+    position        INTEGER NOT NULL DEFAULT 0, -- Display order within the product
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT product_variants_sku_key UNIQUE (sku),
+    -- No two variants of a product with the same option values.
+    CONSTRAINT product_variants_options_key UNIQUE (product_id, options)
+);
+
+-- Every existing product becomes its only variant. Its ID doubles as the SKU
+-- until someone assigns a real one.
+INSERT INTO product_variants (product_id, sku, price, inventory_count)
+SELECT id, id::text, price, inventory_count FROM products;
+
+ALTER TABLE products DROP COLUMN price, DROP COLUMN inventory_count;
+
+-- Images with a variant_id show that variant; the others the product as a whole.
+ALTER TABLE product_images ADD COLUMN variant_id UUID REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE INDEX product_images_variant_id_idx ON product_images (variant_id);
+
+-- Carts hold variants. Each product has exactly one variant at this point.
+ALTER TABLE cart_items ADD COLUMN variant_id UUID REFERENCES product_variants (id) ON DELETE CASCADE;
+UPDATE cart_items ci SET variant_id = v.id FROM product_variants v WHERE v.product_id = ci.product_id;
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_pkey;
+ALTER TABLE cart_items DROP COLUMN product_id;
+ALTER TABLE cart_items ALTER COLUMN variant_id SET NOT NULL;
+ALTER TABLE cart_items ADD PRIMARY KEY (user_id, variant_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS price_history;
+DROP TABLE IF EXISTS scheduled_price_changes;
+DROP INDEX IF EXISTS product_variants_sale_starts_at_idx;
+DROP INDEX IF EXISTS product_variants_sale_ends_at_idx;
+ALTER TABLE product_variants
+    DROP CONSTRAINT IF EXISTS product_variants_sale_check,
+    DROP COLUMN IF EXISTS sale_price,
+    DROP COLUMN IF EXISTS sale_starts_at,
+    DROP COLUMN IF EXISTS sale_ends_at;
@@ -0,0 +1,49 @@
+-- A sale charges sale_price instead of price from sale_starts_at until
+-- sale_ends_at, or until it is ended if that is NULL. price stays the
+-- regular price, which responses show next to the sale price.
+ALTER TABLE product_variants
+    ADD COLUMN sale_price     NUMERIC(12, 2) CHECK (sale_price >= 0),
+    ADD COLUMN sale_starts_at TIMESTAMPTZ,
+    ADD COLUMN sale_ends_at   TIMESTAMPTZ,
+    ADD CONSTRAINT product_variants_sale_check CHECK (
+        (sale_price IS NULL AND sale_starts_at IS NULL AND sale_ends_at IS NULL)
+        OR (sale_price IS NOT NULL AND sale_starts_at IS NOT NULL
+            AND (sale_ends_at IS NULL OR sale_ends_at > sale_starts_at))
+    );
+
+-- The price scheduler looks for sales that started or ended since it last ran.
+CREATE INDEX product_variants_sale_starts_at_idx ON product_variants (sale_starts_at) WHERE sale_starts_at IS NOT NULL;
+CREATE INDEX product_variants_sale_ends_at_idx ON product_variants (sale_ends_at) WHERE sale_ends_at IS NOT NULL;
+
+-- Changes of a variant's regular price that take effect later. The price
+-- scheduler applies each one once effective_at has passed and sets applied_at.
+CREATE TABLE scheduled_price_changes (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    variant_id   UUID NOT NULL REFERENCES product_variants (id) ON DELETE CASCADE,
+    price        NUMERIC(12, 2) NOT NULL CHECK (price >= 0),
+    effective_at TIMESTAMPTZ NOT NULL,
+    created_by   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    applied_at   TIMESTAMPTZ
+);
+
+CREATE INDEX scheduled_price_changes_variant_id_idx ON scheduled_price_changes (variant_id);
+CREATE INDEX scheduled_price_changes_due_idx ON scheduled_price_changes (effective_at) WHERE applied_at IS NULL;
+
+-- Every change of a variant's regular price, whether an admin made it or the
+-- scheduler applied it. Like audit_log's actor_id and entity_id, variant_id,
+-- changed_by and scheduled_price_changes.created_by have no foreign key so the
+-- history outlives deleted variants and users. Deleting a variant does drop
+-- its scheduled changes, applied or not, which nulls scheduled_change_id.
+CREATE TABLE price_history (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    variant_id          UUID NOT NULL,
+    old_price           NUMERIC(12, 2) NOT NULL,
+    new_price           NUMERIC(12, 2) NOT NULL,
+    source              TEXT NOT NULL CHECK (source IN ('admin', 'schedule')),
+    changed_by          TEXT,
+    scheduled_change_id UUID REFERENCES scheduled_price_changes (id) ON DELETE SET NULL,
+    changed_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX price_history_variant_id_changed_at_idx ON price_history (variant_id, changed_at DESC);
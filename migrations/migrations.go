@@ -0,0 +1,10 @@
+// backend/migrations/migrations.go
+
+// Package migrations embeds the versioned SQL schema migrations.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
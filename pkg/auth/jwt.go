@@ -2,67 +2,126 @@
 package auth
 
 import (
-	"backend/internal/models" // We might need the user model for role info
+	"backend/internal/models"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// In a real app, this should be loaded securely from config and be much longer!
-var jwtSecretKey = []byte("a-very-secret-and-long-key-for-amin-n-co")
-
 // Claims defines the data stored inside the JWT.
 type Claims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
+	TokenVersion int    `json:"token_version"` // Must match the user's current version; see UserService.Authenticate
 	jwt.RegisteredClaims
 }
 
+// TokenConfig configures a TokenManager.
+type TokenConfig struct {
+	SigningKey *Key
+	// VerificationKeys are accepted in addition to SigningKey. During a rotation
+	// this holds the previous key until every token it signed has expired.
+	VerificationKeys []*Key
+	TTL              time.Duration
+	Issuer           string
+	Audience         []string // Written to "aud"; validation requires at least one match. Empty disables the check
+	Leeway           time.Duration
+}
+
+// TokenManager issues and validates JWTs with a fixed set of keys.
+type TokenManager struct {
+	signingKey *Key
+	keys       map[string]*Key // By kid, including the signing key
+	methods    []string        // Algorithms of the known keys
+	ttl        time.Duration
+	issuer     string
+	audience   []string
+	leeway     time.Duration
+}
+
+func NewTokenManager(cfg TokenConfig) (*TokenManager, error) {
+	if cfg.SigningKey == nil || !cfg.SigningKey.CanSign() {
+		return nil, errors.New("a signing key with private material is required")
+	}
+	if cfg.TTL <= 0 {
+		return nil, errors.New("token TTL must be positive")
+	}
+
+	m := &TokenManager{
+		signingKey: cfg.SigningKey,
+		keys:       make(map[string]*Key),
+		ttl:        cfg.TTL,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		leeway:     cfg.Leeway,
+	}
+	seen := make(map[string]bool)
+	for _, k := range append([]*Key{cfg.SigningKey}, cfg.VerificationKeys...) {
+		if k.ID == "" {
+			return nil, errors.New("every key needs a non-empty ID")
+		}
+		if _, dup := m.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate key ID %q", k.ID)
+		}
+		m.keys[k.ID] = k
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			m.methods = append(m.methods, k.Algorithm)
+		}
+	}
+	return m, nil
+}
+
 // GenerateToken creates a new signed JWT for a given user.
-func GenerateToken(user *models.User) (string, error) {
-	// For now, we'll hardcode the role. In a real system, you'd look this up.
+func (m *TokenManager) GenerateToken(user *models.User) (string, error) {
 	userRole := "customer" // Default role
-	// if user.IsAdmin { userRole = "admin" }
-
-	// Set the expiration time for the token
-	expirationTime := time.Now().Add(24 * time.Hour) // Token is valid for 24 hours
+	if user.Role != nil && *user.Role != "" {
+		userRole = *user.Role
+	}
 
+	now := time.Now()
 	claims := &Claims{
-		UserID: user.ID,
-		Role:   userRole,
+		UserID:       user.ID,
+		Role:         userRole,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "backend",
+			Subject:   user.ID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    m.issuer,
+			Audience:  m.audience,
 		},
 	}
 
-	// Create a new token object, specifying signing method and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with our secret key
-	tokenString, err := token.SignedString(jwtSecretKey)
-	if err != nil {
-		return "", err
-	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(m.signingKey.Algorithm), claims)
+	// The kid header tells validators which key to use, which is what makes rotation possible.
+	token.Header["kid"] = m.signingKey.ID
 
-	return tokenString, nil
+	return token.SignedString(m.signingKey.signKey)
 }
 
 // ValidateToken parses and validates a JWT string.
 // It returns the claims if the token is valid.
-func ValidateToken(tokenString string) (*Claims, error) {
+func (m *TokenManager) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Make sure the signing method is what we expect
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtSecretKey, nil
-	})
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(m.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(m.leeway),
+	}
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+	if len(m.audience) > 0 {
+		opts = append(opts, jwt.WithAudience(m.audience...))
+	}
 
+	token, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,3 +132,47 @@ func ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// keyFunc picks the verification key named by the token's kid. Tokens without
+// a kid predate rotation support and are checked against the signing key.
+func (m *TokenManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := m.signingKey
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = m.keys[kid]; !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+	}
+	// Make sure the signing method is the one this key is meant for; this stops
+	// algorithm-confusion attacks such as an RSA public key used as an HMAC secret.
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public keys that verify tokens. HMAC keys are never included.
+func (m *TokenManager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range m.orderedKeys() {
+		if jwk, err := k.publicJWK(); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// orderedKeys lists the signing key first, then the rest by kid, for stable output.
+func (m *TokenManager) orderedKeys() []*Key {
+	keys := []*Key{m.signingKey}
+	ids := make([]string, 0, len(m.keys))
+	for id := range m.keys {
+		if id != m.signingKey.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		keys = append(keys, m.keys[id])
+	}
+	return keys
+}
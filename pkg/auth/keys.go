@@ -0,0 +1,176 @@
+// backend/pkg/auth/keys.go
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minHMACSecretLength is the shortest HS256 secret we accept (256 bits).
+const minHMACSecretLength = 32
+
+// Key is a signing or verification key identified by its kid.
+// Keys parsed from a public key can only verify.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey; nil when verify-only
+	verifyKey interface{} // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+// CanSign reports whether the key holds private material.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// NewHMACKey creates an HS256 key from a shared secret.
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < minHMACSecretLength {
+		return nil, fmt.Errorf("HMAC secret for key %q must be at least %d bytes", id, minHMACSecretLength)
+	}
+	return &Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// ParsePrivateKeyPEM parses an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key.
+func ParsePrivateKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("key %q: RSA keys must be at least 2048 bits", id)
+		}
+		return &Key{ID: id, Algorithm: AlgRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Algorithm: AlgEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported private key type %T", id, parsed)
+	}
+}
+
+// ParsePublicKeyPEM parses an RSA or Ed25519 public key, producing a verify-only key.
+func ParsePublicKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: id, Algorithm: AlgRS256, verifyKey: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Algorithm: AlgEdDSA, verifyKey: k}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported public key type %T", id, parsed)
+	}
+}
+
+// LoadKeyFile reads a key from disk. PEM private or public keys are parsed as
+// such; any other content is treated as an HMAC secret (surrounding whitespace trimmed).
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	block, _ := pem.Decode(data)
+	switch {
+	case block == nil:
+		return NewHMACKey(id, bytes.TrimSpace(data))
+	case block.Type == "PUBLIC KEY" || block.Type == "RSA PUBLIC KEY":
+		return ParsePublicKeyPEM(id, data)
+	default:
+		return ParsePrivateKeyPEM(id, data)
+	}
+}
+
+// JWK is a single JSON Web Key (RFC 7517) describing a public key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// publicJWK returns the key's JWK, or an error for symmetric keys, which must never be published.
+func (k *Key) publicJWK() (JWK, error) {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Algorithm,
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Algorithm,
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	default:
+		return JWK{}, errors.New("symmetric keys have no public form")
+	}
+}
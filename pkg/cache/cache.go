@@ -0,0 +1,110 @@
+// backend/pkg/cache/cache.go
+
+// Package cache defines a small key/value cache interface and an in-process
+// LRU implementation of it.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache stores values under string keys, each for at most its TTL. Values
+// are bytes so that an implementation can live outside the process, e.g. in
+// Redis, and be shared by every instance. Callers should treat errors as
+// misses. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value for key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key. A ttl <= 0 means no expiry, although the
+	// entry may still be evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// LRU is an in-process Cache holding at most a fixed number of entries; when
+// full, the least recently used entry is evicted. Expired entries are dropped
+// when they are read or reach the back of the list.
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List // Front is most recently used
+	items      map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time // Zero for no expiry
+}
+
+// NewLRU returns an empty cache that holds up to maxEntries values.
+func NewLRU(maxEntries int) *LRU {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &LRU{maxEntries: maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get returns the stored slice itself; callers must not modify it.
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &lruEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.ll.PushFront(e)
+	for c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back())
+	}
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet dropped.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// remove drops el. Callers hold mu.
+func (c *LRU) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,172 @@
+// backend/pkg/config/loader.go
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// redacted replaces secret values when the configuration is printed.
+const redacted = "[REDACTED]"
+
+// applyDefaults sets every field that has a `default` tag.
+func applyDefaults(v reflect.Value) error {
+	return walk(v, func(f reflect.Value, sf reflect.StructField) error {
+		def, ok := sf.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		if err := setFromString(f, def); err != nil {
+			return fmt.Errorf("bad default for %s: %w", sf.Name, err)
+		}
+		return nil
+	})
+}
+
+// applyEnv overrides fields whose `env` variable is set.
+func applyEnv(v reflect.Value) error {
+	return walk(v, func(f reflect.Value, sf reflect.StructField) error {
+		key := sf.Tag.Get("env")
+		if key == "" {
+			return nil
+		}
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return nil
+		}
+		if err := setFromString(f, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// defaultedSecrets returns the env names of secret fields that still hold a non-empty default.
+func defaultedSecrets(v reflect.Value) []string {
+	var names []string
+	walk(v, func(f reflect.Value, sf reflect.StructField) error {
+		def := sf.Tag.Get("default")
+		if sf.Tag.Get("secret") == "true" && def != "" && f.Kind() == reflect.String && f.String() == def {
+			names = append(names, sf.Tag.Get("env"))
+		}
+		return nil
+	})
+	return names
+}
+
+// Redacted returns a copy of the configuration that is safe to print: secret
+// values are replaced, and URLs only lose their password.
+func (c *Config) Redacted() *Config {
+	cp := *c
+	walk(reflect.ValueOf(&cp).Elem(), func(f reflect.Value, sf reflect.StructField) error {
+		if sf.Tag.Get("secret") != "true" || f.Kind() != reflect.String || f.String() == "" {
+			return nil
+		}
+		if u, err := url.Parse(f.String()); err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				u.User = url.UserPassword(u.User.Username(), "REDACTED")
+				f.SetString(u.String())
+				return nil
+			}
+		}
+		f.SetString(redacted)
+		return nil
+	})
+	return &cp
+}
+
+// walk calls fn for every leaf field, descending into nested config structs.
+func walk(v reflect.Value, fn func(f reflect.Value, sf reflect.StructField) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, sf := v.Field(i), t.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := walk(f, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(f, sf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFromString parses raw into the field according to its type.
+// Slices are comma-separated; maps use "key=value,key=value".
+func setFromString(f reflect.Value, raw string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration like 15s or 5m: %w", err)
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("must be an integer: %w", err)
+		}
+		f.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number: %w", err)
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be a boolean: %w", err)
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		f.Set(reflect.ValueOf(splitList(raw)))
+	case reflect.Map:
+		m, err := parseKeyValues(raw)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported config field type %s", f.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseKeyValues parses "key=value,key=value" into a map.
+func parseKeyValues(value string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(val) == "" {
+			return nil, fmt.Errorf("entry %q must look like key=value", item)
+		}
+		m[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return m, nil
+}
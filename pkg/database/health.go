@@ -0,0 +1,42 @@
+// backend/pkg/database/health.go
+package database
+
+import (
+	"context"
+
+	"backend/pkg/health"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// HealthCheck pings the database and reports the applied migration version
+// and connection pool statistics.
+func HealthCheck(pool *pgxpool.Pool) health.CheckFunc {
+	db := OpenDB(pool) // For CurrentVersion, which is shared with the Migrator
+	return func(ctx context.Context) (interface{}, error) {
+		if err := pool.Ping(ctx); err != nil {
+			return nil, err
+		}
+
+		stats := pool.Stat()
+		details := map[string]interface{}{
+			"pool": map[string]interface{}{
+				"max_conns":           stats.MaxConns(),
+				"total_conns":         stats.TotalConns(),
+				"acquired_conns":      stats.AcquiredConns(),
+				"idle_conns":          stats.IdleConns(),
+				"empty_acquire_count": stats.EmptyAcquireCount(),
+				"acquire_duration_ms": stats.AcquireDuration().Milliseconds(),
+			},
+		}
+
+		// A missing schema_migrations table isn't a connectivity problem, so it
+		// is reported rather than failing the check.
+		if version, err := CurrentVersion(ctx, db); err != nil {
+			details["migration_error"] = err.Error()
+		} else {
+			details["migration_version"] = version
+		}
+		return details, nil
+	}
+}
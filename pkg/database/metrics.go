@@ -0,0 +1,38 @@
+// backend/pkg/database/metrics.go
+package database
+
+import (
+	"backend/pkg/metrics"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RegisterPoolMetrics exposes the connection pool statistics of pool. The
+// values are read from pool.Stat() on every scrape.
+func RegisterPoolMetrics(reg *metrics.Registry, pool *pgxpool.Pool) {
+	stat := func(fn func(s *pgxpool.Stat) float64) func() float64 {
+		return func() float64 { return fn(pool.Stat()) }
+	}
+	reg.MustRegister(
+		metrics.NewGaugeFunc("db_pool_max_connections", "Maximum size of the connection pool.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) })),
+		metrics.NewGaugeFunc("db_pool_total_connections", "Number of connections in the pool, both acquired and idle.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) })),
+		metrics.NewGaugeFunc("db_pool_acquired_connections", "Number of connections currently in use.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) })),
+		metrics.NewGaugeFunc("db_pool_idle_connections", "Number of idle connections.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) })),
+		metrics.NewGaugeFunc("db_pool_constructing_connections", "Number of connections being established.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.ConstructingConns()) })),
+		metrics.NewCounterFunc("db_pool_acquires_total", "Total number of successful connection acquisitions.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) })),
+		metrics.NewCounterFunc("db_pool_empty_acquires_total", "Total number of acquisitions that had to wait for a connection.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) })),
+		metrics.NewCounterFunc("db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.",
+			stat(func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() })),
+		metrics.NewCounterFunc("db_pool_max_lifetime_destroyed_total", "Total number of connections closed due to MaxConnLifetime.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxLifetimeDestroyCount()) })),
+		metrics.NewCounterFunc("db_pool_max_idle_destroyed_total", "Total number of connections closed due to MaxConnIdleTime.",
+			stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxIdleDestroyCount()) })),
+	)
+}
@@ -0,0 +1,274 @@
+// backend/pkg/database/migrate.go
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationLockID is the key for pg_advisory_lock. Any constant works as long
+// as nothing else in the database uses the same one.
+const migrationLockID int64 = 0x6d6967726174 // "migrat"
+
+// migrationFilePattern matches "<version>_<name>.<up|down>.sql".
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // Empty when the migration cannot be rolled back
+}
+
+// MigrationStatus describes a migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations and records them in the schema_migrations table.
+// Every operation holds a Postgres advisory lock, so concurrent runs (e.g.
+// several instances starting with MIGRATE_ON_START) wait for each other.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration // Sorted by version
+}
+
+// NewMigrator loads the migrations found in fsys.
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if len(m.migrations) == 0 {
+		return 0, nil
+	}
+	return m.migrate(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the most recent steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	var n int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// To migrates up or down until exactly the migrations with version <= target are applied.
+func (m *Migrator) To(ctx context.Context, target int64) (int, error) {
+	return m.migrate(ctx, target)
+}
+
+// migrate rolls back applied migrations above target, then applies pending ones up to it.
+func (m *Migrator) migrate(ctx context.Context, target int64) (int, error) {
+	var n int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && mig.Version > target {
+				if err := m.apply(ctx, conn, mig, false); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= target {
+				if err := m.apply(ctx, conn, mig, true); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Status lists every known migration with its applied time, if any.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// CurrentVersion returns the highest applied version, or 0 if none is applied.
+// It does not take the migration lock.
+func CurrentVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	return version.Int64, err
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock.
+// Session-level advisory locks belong to a connection, which is why a *sql.Conn
+// is used rather than the pool.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("could not acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled.
+		if _, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); unlockErr != nil && err == nil {
+			err = fmt.Errorf("could not release migration lock: %w", unlockErr)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// apply runs one migration in its own transaction together with its bookkeeping row.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	script := mig.Up
+	if !up {
+		if mig.Down == "" {
+			return fmt.Errorf("migration %d_%s cannot be rolled back: no down file", mig.Version, mig.Name)
+		}
+		script = mig.Down
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+		return fmt.Errorf("migration %d_%s (%s) failed: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedVersions maps each applied version to when it was applied.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
@@ -2,30 +2,71 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
-	// We use the pgx driver which is highly recommended for PostgreSQL.
-	// The blank import is the standard way to register a driver.
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
-// Connect establishes a connection pool to the PostgreSQL database.
-func Connect(databaseURL string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", databaseURL)
+// PoolOptions configures the connection pool. Zero values keep pgxpool's defaults.
+type PoolOptions struct {
+	MaxConns          int32         // Max number of open connections to the database.
+	MinConns          int32         // Connections kept open even when idle.
+	MaxConnLifetime   time.Duration // Max amount of time a connection may be reused.
+	MaxConnIdleTime   time.Duration // Idle connections above MinConns are closed after this long.
+	HealthCheckPeriod time.Duration // How often idle connections are checked and replaced.
+	// DisableStatementCache sends every query unprepared. Set it behind
+	// transaction-pooling proxies such as PgBouncer, where a prepared
+	// statement may not exist on the next server connection.
+	DisableStatementCache bool
+}
+
+// Connect establishes a connection pool to the PostgreSQL database. Queries
+// use the extended protocol with automatically prepared and cached statements
+// unless DisableStatementCache is set.
+func Connect(ctx context.Context, databaseURL string, opts PoolOptions) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxConns > 0 {
+		cfg.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		cfg.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = opts.HealthCheckPeriod
+	}
+	if opts.DisableStatementCache {
+		cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	// Ping the database to verify the connection is alive.
-	if err = db.Ping(); err != nil {
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, err
 	}
+	return pool, nil
+}
 
-	// Configure the connection pool for better performance.
-	db.SetMaxOpenConns(25)                 // Max number of open connections to the database.
-	db.SetMaxIdleConns(25)                 // Max number of connections in the idle connection pool.
-	db.SetConnMaxLifetime(5 * time.Minute) // Max amount of time a connection may be reused.
-
-	return db, nil
+// OpenDB returns a database/sql handle that borrows connections from pool, for
+// code written against database/sql such as the Migrator. Closing it does not
+// close the pool.
+func OpenDB(pool *pgxpool.Pool) *sql.DB {
+	return stdlib.OpenDBFromPool(pool)
 }
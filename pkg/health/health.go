@@ -0,0 +1,107 @@
+// backend/pkg/health/health.go
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status values used in reports.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc probes one dependency. It returns optional details to include in
+// the report (e.g. pool statistics) and an error when the dependency is unusable.
+type CheckFunc func(ctx context.Context) (details interface{}, err error)
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status     string      `json:"status"`
+	Required   bool        `json:"required"`
+	DurationMS int64       `json:"duration_ms"`
+	Error      string      `json:"error,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
+}
+
+// Report is the combined result of all registered checks. Status is down
+// if any required check failed; optional checks only show up in Checks.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type check struct {
+	name     string
+	required bool
+	timeout  time.Duration
+	fn       CheckFunc
+}
+
+// Registry holds the dependency checks that make up readiness.
+// Components register their checks at startup; Run may be called concurrently.
+type Registry struct {
+	mu     sync.RWMutex
+	checks []check
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check. Each run of fn is bounded by timeout.
+func (r *Registry) Register(name string, required bool, timeout time.Duration, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, required: required, timeout: timeout, fn: fn})
+}
+
+// Run executes all checks in parallel and combines their results.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if c.required && results[i].Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := c.fn(ctx)
+	result := CheckResult{
+		Status:     StatusUp,
+		Required:   c.required,
+		DurationMS: time.Since(start).Milliseconds(),
+		Details:    details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+// backend/pkg/jsonutil/decode.go
+package jsonutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes caps request bodies read by Decode.
+const DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// DecodeError describes why a request body was rejected. Status is the HTTP
+// status to answer with and Field the JSON path of the offending field, if any.
+type DecodeError struct {
+	Status  int
+	Message string
+	Field   string
+}
+
+func (e *DecodeError) Error() string {
+	return e.Message
+}
+
+// Decode reads a single JSON object from the request body into dst, capped at
+// DefaultMaxBodyBytes. It rejects a missing or non-JSON Content-Type, unknown
+// fields and trailing data. Errors are *DecodeError.
+func Decode(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	return DecodeWithLimit(w, r, dst, DefaultMaxBodyBytes)
+}
+
+// DecodeWithLimit is Decode with a custom body size limit.
+func DecodeWithLimit(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	// A missing Content-Type fails to parse, so it is refused like a wrong one.
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return &DecodeError{Status: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json"}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err, maxBytes)
+	}
+	// A second value, even a valid one, means the body was not a single object.
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return decodeError(err, maxBytes)
+		}
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Request body must only contain a single JSON object"}
+	}
+	return nil
+}
+
+// decodeError turns encoding/json errors into messages safe to show clients.
+func decodeError(err error, maxBytes int64) *DecodeError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Request body contains badly-formed JSON (at position %d)", syntaxErr.Offset)}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Request body contains badly-formed JSON"}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return &DecodeError{Status: http.StatusBadRequest, Message: "Request body must be a JSON " + jsonType(typeErr.Type.Kind().String())}
+		}
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("Field %q must be a %s, got %s", typeErr.Field, jsonType(typeErr.Type.Kind().String()), typeErr.Value),
+			Field:   typeErr.Field,
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for this one.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &DecodeError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Request body contains unknown field %q", field), Field: field}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Request body must not be empty"}
+	case errors.As(err, &maxErr):
+		return &DecodeError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Request body must not be larger than %d bytes", maxBytes)}
+	default:
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Invalid request payload"}
+	}
+}
+
+// jsonType names a Go kind the way a client would think of it.
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "bool":
+		return "boolean"
+	case kind == "slice", kind == "array":
+		return "array"
+	case kind == "struct", kind == "map":
+		return "object"
+	}
+	return kind
+}
+
+// RespondWithDecodeError answers with the status and message of a *DecodeError,
+// including the offending field when known; other errors become a plain 400.
+func RespondWithDecodeError(w http.ResponseWriter, err error) {
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	body := map[string]string{"error": derr.Message}
+	if derr.Field != "" {
+		body["field"] = derr.Field
+	}
+	RespondWithJSON(w, derr.Status, body)
+}
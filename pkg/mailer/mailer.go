@@ -0,0 +1,120 @@
+// backend/pkg/mailer/mailer.go
+package mailer
+
+import (
+	"backend/pkg/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer is meant for local development: it never talks to a mail server.
+// Messages are written to the log, or saved as .eml files when Dir is set.
+type LogMailer struct {
+	From string
+	Dir  string
+}
+
+func NewLogMailer(from, dir string) *LogMailer {
+	return &LogMailer{From: from, Dir: dir}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.Dir == "" {
+		logging.FromContext(ctx).Info("email", slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFilename(msg.To))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, buildMessage(m.From, msg), 0o644); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("wrote email", slog.String("to", msg.To), slog.String("path", path))
+	return nil
+}
+
+// SMTPMailer sends email through an SMTP relay using PLAIN auth when credentials are set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// net/smtp has no context support, so run the send in the background and
+	// stop waiting if the caller gives up.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, buildMessage(m.From, msg))
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMessage renders an RFC 5322 message with UTF-8 headers and body.
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + stripNewlines(from) + "\r\n")
+	b.WriteString("To: " + stripNewlines(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// stripNewlines prevents header injection through user-supplied values.
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		}
+		return '_'
+	}, s)
+}
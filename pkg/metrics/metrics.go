@@ -0,0 +1,328 @@
+// backend/pkg/metrics/metrics.go
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets suit request and query latencies measured in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector is a metric family that can write itself in the Prometheus text
+// exposition format.
+type Collector interface {
+	Name() string
+	writeTo(w *bufio.Writer)
+}
+
+// Registry holds collectors and serves them at /metrics.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Default is the registry that package-level collectors register with.
+var Default = NewRegistry()
+
+// Register adds c to the registry. Names must be unique.
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[c.Name()]; ok {
+		return fmt.Errorf("metric %q is already registered", c.Name())
+	}
+	r.collectors[c.Name()] = c
+	return nil
+}
+
+// MustRegister registers collectors and panics on a duplicate name. Meant for
+// package-level metrics, where a duplicate is a programming error.
+func (r *Registry) MustRegister(cs ...Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// MustRegister registers collectors with the Default registry.
+func MustRegister(cs ...Collector) {
+	Default.MustRegister(cs...)
+}
+
+// WriteText writes every collector, sorted by name, in the text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]Collector, len(names))
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.writeTo(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// --- Counters ---
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	bits uint64 // math.Float64bits of the value
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add increases the counter. Negative values are ignored.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	addFloat(&c.bits, v)
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	family
+	children sync.Map // label key => *Counter
+}
+
+// NewCounterVec creates a counter family. Without labels it is a single
+// counter that is exposed as 0 until first incremented.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{family: family{name: name, help: help, labels: labels}}
+	if len(labels) == 0 {
+		v.WithLabelValues()
+	}
+	return v
+}
+
+// WithLabelValues returns the counter for the given label values, creating it
+// on first use. Values must be passed in the order the labels were declared.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := v.key(values)
+	if c, ok := v.children.Load(key); ok {
+		return c.(*Counter)
+	}
+	c, _ := v.children.LoadOrStore(key, &Counter{})
+	return c.(*Counter)
+}
+
+func (v *CounterVec) writeTo(w *bufio.Writer) {
+	v.header(w, "counter")
+	for _, key := range sortedKeys(&v.children) {
+		c, _ := v.children.Load(key)
+		writeSample(w, v.name, key, "", c.(*Counter).Value())
+	}
+}
+
+// --- Histograms ---
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	buckets []float64
+	counts  []uint64 // Per bucket, not cumulative; the last entry is +Inf
+	count   uint64
+	sumBits uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v) // First bucket with upper bound >= v
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	addFloat(&h.sumBits, v)
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	family
+	buckets  []float64
+	children sync.Map // label key => *Histogram
+}
+
+// NewHistogramVec creates a histogram family. Buckets must be sorted; nil
+// means DefaultBuckets.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &HistogramVec{family: family{name: name, help: help, labels: labels}, buckets: buckets}
+}
+
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := v.key(values)
+	if h, ok := v.children.Load(key); ok {
+		return h.(*Histogram)
+	}
+	h, _ := v.children.LoadOrStore(key, newHistogram(v.buckets))
+	return h.(*Histogram)
+}
+
+func (v *HistogramVec) writeTo(w *bufio.Writer) {
+	v.header(w, "histogram")
+	for _, key := range sortedKeys(&v.children) {
+		value, _ := v.children.Load(key)
+		h := value.(*Histogram)
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += atomic.LoadUint64(&h.counts[i])
+			writeSample(w, v.name+"_bucket", key, `le="`+formatFloat(upper)+`"`, float64(cumulative))
+		}
+		cumulative += atomic.LoadUint64(&h.counts[len(h.buckets)])
+		writeSample(w, v.name+"_bucket", key, `le="+Inf"`, float64(cumulative))
+		writeSample(w, v.name+"_sum", key, "", math.Float64frombits(atomic.LoadUint64(&h.sumBits)))
+		writeSample(w, v.name+"_count", key, "", float64(atomic.LoadUint64(&h.count)))
+	}
+}
+
+// --- Function-backed metrics ---
+
+// Func reports a value read at scrape time, e.g. from sql.DB.Stats. It is
+// exposed as a gauge or, for values that only go up, a counter.
+type Func struct {
+	family
+	kind string
+	fn   func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *Func {
+	return &Func{family: family{name: name, help: help}, kind: "gauge", fn: fn}
+}
+
+func NewCounterFunc(name, help string, fn func() float64) *Func {
+	return &Func{family: family{name: name, help: help}, kind: "counter", fn: fn}
+}
+
+func (f *Func) writeTo(w *bufio.Writer) {
+	f.header(w, f.kind)
+	writeSample(w, f.name, "", "", f.fn())
+}
+
+// --- Helpers ---
+
+// family holds what every metric family has in common.
+type family struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (f *family) Name() string { return f.name }
+
+func (f *family) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, kind)
+}
+
+// key renders label values as the `name="value",...` string used both as the
+// child's map key and in the output.
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	var b strings.Builder
+	for i, label := range f.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(values[i]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+func sortedKeys(m *sync.Map) []string {
+	var keys []string
+	m.Range(func(k, _ interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func writeSample(w *bufio.Writer, name, labels, extra string, value float64) {
+	w.WriteString(name)
+	if labels != "" || extra != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		if labels != "" && extra != "" {
+			w.WriteByte(',')
+		}
+		w.WriteString(extra)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelEscaper.Replace(s) }
+
+// addFloat atomically adds v to the float64 stored in bits.
+func addFloat(bits *uint64, v float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		updated := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(bits, old, updated) {
+			return
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// backend/pkg/openapi/reflect.go
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// reflector derives schemas from Go types the way encoding/json would
+// serialize them. Named structs become components; `validate` tags (see
+// package validate) become constraints.
+//
+// A struct with validate tags is treated as a request body: a field is required
+// when its tag says so. Any other struct is a response: every field that is
+// not a pointer and not omitempty is always present, so it is required.
+type reflector struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newReflector(schemas map[string]*Schema) *reflector {
+	return &reflector{schemas: schemas, names: make(map[reflect.Type]string)}
+}
+
+func (r *reflector) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return r.schemaFor(reflect.TypeOf(v))
+}
+
+func (r *reflector) schemaFor(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{} // Any JSON value
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := r.schemaFor(t.Elem())
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+		return s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		format := ""
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			format = "int64"
+		}
+		return &Schema{Type: "integer", Format: format}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.register(t)}
+	}
+	return &Schema{}
+}
+
+// register adds a named struct to the components once and returns its name.
+func (r *reflector) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	// Unexported types (doc-only shapes such as error bodies) still get a
+	// conventional component name.
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := r.schemas[name]; taken {
+		// Same name in another package, e.g. models.Product vs service.Product.
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	r.names[t] = name
+	r.schemas[name] = &Schema{} // Placeholder so recursive types terminate
+	*r.schemas[name] = *r.structSchema(t)
+	return name
+}
+
+func (r *reflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	isRequest := hasValidateTags(t)
+	r.addFields(s, t, isRequest)
+	return s
+}
+
+func (r *reflector) addFields(s *Schema, t reflect.Type, isRequest bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		// Embedded structs without a JSON name are flattened, as encoding/json does.
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				r.addFields(s, ft, isRequest)
+				continue
+			}
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		prop := r.schemaFor(sf.Type)
+		rules := sf.Tag.Get("validate")
+		applyRules(prop, rules)
+		s.Properties[name] = prop
+
+		omitempty := strings.Contains(opts, "omitempty")
+		pointer := sf.Type.Kind() == reflect.Ptr
+		if isRequest && hasRule(rules, "required") && !pointer ||
+			!isRequest && !omitempty && !pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyRules translates validate tags into schema constraints.
+func applyRules(s *Schema, rules string) {
+	if rules == "" || s.Ref != "" {
+		return
+	}
+	typ, _ := s.Type.(string)
+	if list, ok := s.Type.([]string); ok {
+		typ = list[0]
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if typ == "string" {
+				s.MinLength = intPtr(1)
+			}
+			if typ == "array" {
+				s.MinItems = intPtr(1)
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			switch typ {
+			case "string":
+				setBound(name, &s.MinLength, &s.MaxLength, int(n))
+			case "array":
+				setBound(name, &s.MinItems, &s.MaxItems, int(n))
+			default:
+				if name == "min" {
+					s.Minimum = &n
+				} else {
+					s.Maximum = &n
+				}
+			}
+		case "email":
+			s.Format = "email"
+		case "uuid":
+			s.Format = "uuid"
+		case "oneof":
+			for _, option := range strings.Fields(arg) {
+				s.Enum = append(s.Enum, option)
+			}
+		case "nonzero":
+			s.Description = strings.TrimSpace(s.Description + " Must not be zero.")
+		}
+	}
+}
+
+func setBound(rule string, min, max **int, n int) {
+	if rule == "min" {
+		*min = &n
+	} else {
+		*max = &n
+	}
+}
+
+func hasValidateTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("validate"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRule(rules, rule string) bool {
+	for _, r := range strings.Split(rules, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func intPtr(n int) *int { return &n }
@@ -0,0 +1,54 @@
+// backend/pkg/tracing/sql.go
+package tracing
+
+import "strings"
+
+// SanitizeSQL prepares a query for use as a span attribute: whitespace runs are
+// collapsed and string and numeric literals are replaced with ?. Bind
+// parameters ($1, $2, ...) are kept, since their values are never included.
+func SanitizeSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '\'':
+			// Skip to the closing quote; '' is an escaped quote inside the literal.
+			for i++; i < len(query); i++ {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			c = '?'
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			for i+1 < len(query) && (isDigit(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// isIdentChar reports whether c can precede a digit inside an identifier or a
+// bind parameter such as $1, where the digit is not a literal.
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
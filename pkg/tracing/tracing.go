@@ -0,0 +1,77 @@
+// backend/pkg/tracing/tracing.go
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Options configures the tracer provider.
+type Options struct {
+	Exporter     string // "none", "stdout" or "otlp"
+	ServiceName  string
+	SampleRatio  float64 // Fraction of new traces to record; incoming sampled traces are always kept
+	OTLPEndpoint string  // host:port of an OTLP/HTTP collector
+	OTLPInsecure bool    // Use plain HTTP instead of HTTPS
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator (traceparent/tracestate headers). With the "none" exporter spans
+// are still created, so trace IDs propagate and show up in logs, but nothing is
+// exported. The returned function flushes pending spans and must be called on
+// shutdown.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+
+	switch opts.Exporter {
+	case "none":
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		// Synchronous export keeps the output next to the request's log lines.
+		providerOpts = append(providerOpts, sdktrace.WithSyncer(exporter))
+	case "otlp":
+		clientOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.OTLPEndpoint)}
+		if opts.OTLPInsecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, err
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,255 @@
+// backend/pkg/validate/validate.go
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Struct checks the `validate` tags on the fields of v, a struct or a pointer
+// to one, and returns a message per invalid field keyed by its JSON path
+// (e.g. "items[2].quantity"). It returns nil when everything is valid, and an
+// error for a tag it doesn't understand.
+//
+// Rules are comma-separated:
+//
+//	required    strings must not be blank, slices must not be empty
+//	min=N,max=N string length in characters, slice length, or numeric value
+//	nonzero     numbers must not be 0
+//	email       a bare email address with a dotted domain
+//	uuid        a canonical UUID
+//	oneof=a b c the value must be one of the space-separated options
+//
+// Other rules are skipped for empty strings unless required is also set, so
+// optional fields only need to be valid when they are present. Nil pointers
+// are skipped entirely, which suits partial updates; a non-nil pointer is
+// checked like the value it points to. Nested structs and slices of structs
+// are checked recursively.
+func Struct(v interface{}) (map[string]string, error) {
+	errs := make(map[string]string)
+	if err := walkStruct(reflect.ValueOf(v), "", errs); err != nil {
+		return nil, err
+	}
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return errs, nil
+}
+
+func walkStruct(v reflect.Value, prefix string, errs map[string]string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := jsonName(sf)
+		if name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+
+		if tag := sf.Tag.Get("validate"); tag != "" {
+			msg, err := checkRules(f, tag)
+			if err != nil {
+				return fmt.Errorf("validate: field %q: %w", path, err)
+			}
+			if msg != "" {
+				errs[path] = msg
+				continue
+			}
+		}
+
+		switch f.Kind() {
+		case reflect.Struct:
+			if err := walkStruct(f, path, errs); err != nil {
+				return err
+			}
+		case reflect.Slice, reflect.Array:
+			for j := 0; j < f.Len(); j++ {
+				if err := walkStruct(f.Index(j), path+"["+strconv.Itoa(j)+"]", errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRules returns the message for the first rule f breaks, or "".
+func checkRules(f reflect.Value, tag string) (string, error) {
+	rules := strings.Split(tag, ",")
+	required := false
+	for _, rule := range rules {
+		if rule == "required" {
+			required = true
+		}
+	}
+
+	if isEmpty(f) {
+		if required {
+			return "is required", nil
+		}
+		if f.Kind() == reflect.String {
+			return "", nil
+		}
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		var msg string
+		switch name {
+		case "required":
+		case "min", "max":
+			var err error
+			if msg, err = checkBound(f, name, arg); err != nil {
+				return "", err
+			}
+		case "nonzero":
+			if isNumber(f) && f.IsZero() {
+				msg = "must not be zero"
+			}
+		case "email":
+			if !Email(f.String()) {
+				msg = "is not a valid email address"
+			}
+		case "uuid":
+			if !UUID(f.String()) {
+				msg = "must be a valid UUID"
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			if !containsString(options, fmt.Sprint(f.Interface())) {
+				msg = "must be one of: " + strings.Join(options, ", ")
+			}
+		default:
+			return "", fmt.Errorf("unknown rule %q", name)
+		}
+		if msg != "" {
+			return msg, nil
+		}
+	}
+	return "", nil
+}
+
+func checkBound(f reflect.Value, rule, arg string) (string, error) {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return "", fmt.Errorf("bad %s argument %q", rule, arg)
+	}
+
+	var n float64
+	var unit string
+	switch f.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(f.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, unit = float64(f.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(f.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(f.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = f.Float()
+	default:
+		return "", fmt.Errorf("%s does not apply to %s", rule, f.Kind())
+	}
+
+	if rule == "min" && n < limit {
+		if unit != "" {
+			return "must be at least " + arg + unit, nil
+		}
+		return "must be at least " + arg, nil
+	}
+	if rule == "max" && n > limit {
+		if f.Kind() == reflect.String {
+			return "is too long", nil
+		}
+		if unit != "" {
+			return "must be at most " + arg + unit, nil
+		}
+		return "must be at most " + arg, nil
+	}
+	return "", nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Email reports whether s is a bare address ("a@example.com", not
+// "Name <a@example.com>") whose domain contains a dot.
+func Email(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	at := strings.LastIndex(s, "@")
+	return strings.Contains(s[at+1:], ".")
+}
+
+// UUID reports whether s is a canonical UUID.
+func UUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+func isEmpty(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(f.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return f.Len() == 0
+	}
+	return false
+}
+
+func isNumber(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// jsonName is the field's name in request bodies.
+func jsonName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return sf.Name
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}